@@ -1,6 +1,7 @@
 package queue
 
 import (
+	"errors"
 	"math/rand"
 	"sync"
 	"testing"
@@ -10,36 +11,37 @@ import (
 // test variables
 const aqsize int = 8
 
-
 // ==================
 // Asynchronous Tests
 // ==================
 
 // - blocking with no delays
-func producer1(q SynchronizedQueue, wg *sync.WaitGroup) {
+func producer1(q SynchronizedQueue[interface{}], wg *sync.WaitGroup) {
 	// fill the queue with ints
-	for i:=0;i<q.Cap();i++ {
+	for i := 0; i < q.Cap(); i++ {
 		q.Put(i)
 	}
 
-	// cleanup 
-	// for channels, this closes it for further Puts
+	// cleanup
+	// this closes it for further Puts
 	// any remaing data is still available for Gets
-	// it is a noop for the mutex/condition variable methods
 	q.Close()
 
 	// mark it done
 	wg.Done()
 }
 
-func consumer1(q SynchronizedQueue, t *testing.T, wg *sync.WaitGroup)  {
+func consumer1(q SynchronizedQueue[interface{}], t *testing.T, wg *sync.WaitGroup) {
 	// consume all items
-	for i:=0;i<q.Cap();i++ {
-		value := q.Get()
+	for i := 0; i < q.Cap(); i++ {
+		value, err := q.Get()
+		if err != nil {
+			t.Error(err)
+		}
 		// convert to int
 		v := value.(int)
 		if v != i {
-			t.Error("v should == i",v,i)
+			t.Error("v should == i", v, i)
 		}
 	}
 	wg.Done()
@@ -48,7 +50,7 @@ func consumer1(q SynchronizedQueue, t *testing.T, wg *sync.WaitGroup)  {
 // - blocking with native ints, no delays
 func producer2(q *NativeIntQueue, wg *sync.WaitGroup) {
 	// fill the queue with ints
-	for i:=0;i<q.Cap();i++ {
+	for i := 0; i < q.Cap(); i++ {
 		q.Put(i)
 	}
 	wg.Done()
@@ -56,85 +58,180 @@ func producer2(q *NativeIntQueue, wg *sync.WaitGroup) {
 
 func consumer2(q *NativeIntQueue, t *testing.T, wg *sync.WaitGroup) {
 	// consume all items
-	for i:=0;i<q.Cap();i++ {
-		v := q.Get()
+	for i := 0; i < q.Cap(); i++ {
+		v, err := q.Get()
+		if err != nil {
+			t.Error(err)
+		}
 		if v != i {
-			t.Error("v should == i",v,i)
+			t.Error("v should == i", v, i)
 		}
 	}
 	wg.Done()
 }
 
 // 3 - blocking with random time delays
-func producer3(q SynchronizedQueue, wg *sync.WaitGroup) {
+func producer3(q SynchronizedQueue[interface{}], wg *sync.WaitGroup) {
 	// fill the queue with ints
-	for i:=0;i<q.Cap();i++ {
+	for i := 0; i < q.Cap(); i++ {
 		time.Sleep(time.Duration(rand.Int63n(50)) * time.Millisecond)
 		q.Put(i)
 	}
 
-	// cleanup 
-	// for channels, this closes it for further Puts
+	// cleanup
+	// this closes it for further Puts
 	// any remaing data is still available for Gets
-	// it is a noop for the mutex/condition variable methods
 	q.Close()
-	
+
 	// mark it done
 	wg.Done()
 }
 
-func consumer3(q SynchronizedQueue, t *testing.T, wg *sync.WaitGroup)  {
+func consumer3(q SynchronizedQueue[interface{}], t *testing.T, wg *sync.WaitGroup) {
 	// consume all items
-	for i:=0;i<q.Cap();i++ {
+	for i := 0; i < q.Cap(); i++ {
 		time.Sleep(time.Duration(rand.Int63n(50)) * time.Millisecond)
-		value := q.Get()
+		value, err := q.Get()
+		if err != nil {
+			t.Error(err)
+		}
 		// convert to int
 		v := value.(int)
 		if v != i {
-			t.Error("v should == i",v,i)
+			t.Error("v should == i", v, i)
 		}
 	}
 	wg.Done()
 }
 
-
 // 3 - blocking with random time delays
 func producer4(q *NativeIntQueue, wg *sync.WaitGroup) {
 	// fill the queue with ints
-	for i:=0;i<q.Cap();i++ {
+	for i := 0; i < q.Cap(); i++ {
 		time.Sleep(time.Duration(rand.Int63n(50)) * time.Millisecond)
 		q.Put(i)
 	}
 
-	// cleanup 
-	// for channels, this closes it for further Puts
+	// cleanup
+	// this closes it for further Puts
 	// any remaing data is still available for Gets
-	// it is a noop for the mutex/condition variable methods
 	q.Close()
-	
+
 	// mark it done
 	wg.Done()
 }
 
-func consumer4(q *NativeIntQueue, t *testing.T, wg *sync.WaitGroup)  {
+func consumer4(q *NativeIntQueue, t *testing.T, wg *sync.WaitGroup) {
 	// consume all items
-	for i:=0;i<q.Cap();i++ {
+	for i := 0; i < q.Cap(); i++ {
 		time.Sleep(time.Duration(rand.Int63n(50)) * time.Millisecond)
-		value := q.Get()
+		value, err := q.Get()
+		if err != nil {
+			t.Error(err)
+		}
 		// convert to int
 		if value != i {
-			t.Error("v should == i",value,i)
+			t.Error("v should == i", value, i)
+		}
+	}
+	wg.Done()
+}
+
+// 5 - single producer single consumer, going around the ring a few times
+func producer5(q *SPSCQueue[int], delay bool, wg *sync.WaitGroup) {
+	for i := 0; i < 4*q.Cap(); i++ {
+		if delay {
+			time.Sleep(time.Duration(rand.Int63n(5)) * time.Millisecond)
+		}
+		q.Put(i)
+	}
+
+	// cleanup
+	// this closes it for further Puts
+	// any remaing data is still available for Gets
+	q.Close()
+
+	// mark it done
+	wg.Done()
+}
+
+func consumer5(q *SPSCQueue[int], delay bool, t *testing.T, wg *sync.WaitGroup) {
+	// consume all items
+	for i := 0; i < 4*q.Cap(); i++ {
+		if delay {
+			time.Sleep(time.Duration(rand.Int63n(5)) * time.Millisecond)
+		}
+		v, err := q.Get()
+		if err != nil {
+			t.Error(err)
+		}
+		if v != i {
+			t.Error("v should == i", v, i)
 		}
 	}
+
+	// nothing is left once the producer has closed it
+	_, err := q.Get()
+	if !errors.Is(err, ErrClosed) {
+		t.Error("err should be ErrClosed", err)
+	}
 	wg.Done()
 }
 
-func async1(t *testing.T, q SynchronizedQueue) {
+// 6 - several producers and consumers, each value is got exactly once
+func async6(t *testing.T, q SynchronizedQueue[int], producers, consumers int) {
+	var pwg sync.WaitGroup
+	var cwg sync.WaitGroup
+	var mtx sync.Mutex
+
+	count := 4 * q.Cap()
+	seen := make([]int, producers*count)
+
+	pwg.Add(producers)
+	for p := 0; p < producers; p++ {
+		go func(base int) {
+			defer pwg.Done()
+			for i := 0; i < count; i++ {
+				q.Put(base + i)
+			}
+		}(p * count)
+	}
+
+	cwg.Add(consumers)
+	for c := 0; c < consumers; c++ {
+		go func() {
+			defer cwg.Done()
+			for {
+				v, err := q.Get()
+				if err != nil {
+					// closed and drained
+					return
+				}
+				mtx.Lock()
+				seen[v]++
+				mtx.Unlock()
+			}
+		}()
+	}
+
+	// close once everything is in so the consumers stop
+	pwg.Wait()
+	q.Close()
+	cwg.Wait()
+
+	for v, n := range seen {
+		if n != 1 {
+			t.Error("each value should be got once", v, n)
+		}
+	}
+}
+
+func async1(t *testing.T, q SynchronizedQueue[interface{}]) {
 	var wg sync.WaitGroup
 
 	wg.Add(2)
-	go producer1(q,&wg)
-	go consumer1(q,t,&wg)
+	go producer1(q, &wg)
+	go consumer1(q, t, &wg)
 	wg.Wait()
 }
 
@@ -143,16 +240,16 @@ func async2(t *testing.T, q *NativeIntQueue) {
 
 	wg.Add(2)
 	go producer2(q, &wg)
-	go consumer2(q,t,&wg)
+	go consumer2(q, t, &wg)
 	wg.Wait()
 }
 
-func async3(t *testing.T, q SynchronizedQueue) {
+func async3(t *testing.T, q SynchronizedQueue[interface{}]) {
 	var wg sync.WaitGroup
 
 	wg.Add(2)
 	go producer3(q, &wg)
-	go consumer3(q,t,&wg)
+	go consumer3(q, t, &wg)
 	wg.Wait()
 }
 
@@ -161,39 +258,97 @@ func async4(t *testing.T, q *NativeIntQueue) {
 
 	wg.Add(2)
 	go producer4(q, &wg)
-	go consumer4(q,t,&wg)
+	go consumer4(q, t, &wg)
+	wg.Wait()
+}
+
+func async5(t *testing.T, q *SPSCQueue[int], delay bool) {
+	var wg sync.WaitGroup
+
+	wg.Add(2)
+	go producer5(q, delay, &wg)
+	go consumer5(q, delay, t, &wg)
 	wg.Wait()
 }
 
 func TestChannelAsync(t *testing.T) {
-	async1(t,NewChannelQueue(aqsize))
-	async3(t,NewChannelQueue(aqsize))
+	async1(t, NewChannelQueue(aqsize))
+	async3(t, NewChannelQueue(aqsize))
 }
 
 func TestListAsync(t *testing.T) {
-	async1(t,NewSyncList(aqsize))
-	async3(t,NewSyncList(aqsize))
+	async1(t, NewSyncList(aqsize))
+	async3(t, NewSyncList(aqsize))
 }
 func TestCircularAsync(t *testing.T) {
-	async1(t,NewSyncCircular(aqsize))
-	async3(t,NewSyncCircular(aqsize))
+	async1(t, NewSyncCircular(aqsize))
+	async3(t, NewSyncCircular(aqsize))
 }
 
 func TestRingAsync(t *testing.T) {
-	async1(t,NewSyncRing(aqsize))
-	async3(t,NewSyncRing(aqsize))
+	async1(t, NewSyncRing(aqsize))
+	async3(t, NewSyncRing(aqsize))
 }
 
 func TestSliceAsync(t *testing.T) {
-	async1(t,NewSyncSlice(aqsize))
-	async3(t,NewSyncSlice(aqsize))
+	async1(t, NewSyncSlice(aqsize))
+	async3(t, NewSyncSlice(aqsize))
 }
 func TestComboAsync(t *testing.T) {
-	async1(t,NewSyncCircular(aqsize))
-	async3(t,NewSyncList(aqsize))
+	async1(t, NewSyncCircular(aqsize))
+	async3(t, NewSyncList(aqsize))
 }
 
 func TestNativeAsync(t *testing.T) {
-	async2(t,NewNativeQueue(aqsize))
-	async4(t,NewNativeQueue(aqsize))
-}
\ No newline at end of file
+	async2(t, NewNativeQueue(aqsize))
+	async4(t, NewNativeQueue(aqsize))
+}
+
+// a Put racing Close either fails or its element is got
+func async7(t *testing.T, q *SPSCQueue[int]) {
+	var wg sync.WaitGroup
+	var accepted, got int
+
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; ; i++ {
+			if err := q.TryPut(i); errors.Is(err, ErrClosed) {
+				return
+			} else if err == nil {
+				accepted++
+			}
+			if i == 500 {
+				go q.Close()
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for {
+			if _, err := q.Get(); err != nil {
+				return
+			}
+			got++
+		}
+	}()
+	wg.Wait()
+
+	if got != accepted {
+		t.Error("every accepted element should be got", accepted, got)
+	}
+}
+
+func TestSPSCAsync(t *testing.T) {
+	async5(t, NewSPSCQueueOf[int](aqsize), false)
+	async5(t, NewSPSCQueueOf[int](aqsize), true)
+	for i := 0; i < 100; i++ {
+		async7(t, NewSPSCQueueOf[int](aqsize))
+	}
+}
+
+func TestMPMCAsync(t *testing.T) {
+	async1(t, NewMPMCQueue(aqsize))
+	async3(t, NewMPMCQueue(aqsize))
+	async6(t, NewMPMCQueueOf[int](aqsize), 4, 4)
+}
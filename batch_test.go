@@ -0,0 +1,185 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// test variables
+const bqcap int = 8
+
+// test the non-blocking batch operations
+func batch1(t *testing.T, q SynchronizedQueue[int]) {
+	values := []int{0, 1, 2, 3, 4, 5}
+
+	// everything fits
+	n, err := q.PutMany(values)
+	if err != nil || n != len(values) {
+		t.Error("PutMany should add all values", n, err)
+	}
+
+	// only part of it fits, wrapping the circular buffers
+	n, err = q.PutMany([]int{6, 7, 8, 9})
+	if !errors.Is(err, ErrFull) || n != 2 {
+		t.Error("PutMany should add 2 values and return ErrFull", n, err)
+	}
+	if q.Len() != q.Cap() {
+		t.Error("length should == capacity", q.Len())
+	}
+
+	// take a few, max is the limit
+	dst := make([]int, bqcap)
+	n, err = q.GetMany(dst, 3)
+	if err != nil || n != 3 {
+		t.Error("GetMany should return 3 values", n, err)
+	}
+	for i := 0; i < n; i++ {
+		if dst[i] != i {
+			t.Error("dst[i] should == i", dst[i], i)
+		}
+	}
+
+	// refill across the end of the buffer
+	n, err = q.PutMany([]int{8, 9, 10})
+	if err != nil || n != 3 {
+		t.Error("PutMany should add 3 values", n, err)
+	}
+
+	// take everything, len(dst) is the limit
+	n, err = q.GetMany(dst, 100)
+	if err != nil || n != bqcap {
+		t.Error("GetMany should return a full buffer", n, err)
+	}
+	for i := 0; i < n; i++ {
+		if dst[i] != i+3 {
+			t.Error("dst[i] should == i+3", dst[i], i+3)
+		}
+	}
+
+	// nothing left
+	_, err = q.GetMany(dst, 1)
+	if !errors.Is(err, ErrEmpty) {
+		t.Error("err should be ErrEmpty", err)
+	}
+}
+
+// test the blocking batch operations
+func batch2(t *testing.T, q SynchronizedQueue[int]) {
+	var wg sync.WaitGroup
+
+	// a batch Get waits for min elements
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		dst := make([]int, bqcap)
+		n, err := q.GetManyContext(context.Background(), dst, 3, bqcap)
+		if err != nil || n < 3 {
+			t.Error("GetManyContext should return at least 3 values", n, err)
+		}
+		for i := 0; i < n; i++ {
+			if dst[i] != i {
+				t.Error("dst[i] should == i", dst[i], i)
+			}
+		}
+	}()
+
+	// put them one at a time so the getter has to keep waiting
+	for i := 0; i < 3; i++ {
+		time.Sleep(5 * time.Millisecond)
+		q.Put(i)
+	}
+	wg.Wait()
+
+	// a batch Put waits for room for at least one element
+	q.PutMany([]int{0, 1, 2, 3, 4, 5, 6, 7})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		n, err := q.PutManyContext(context.Background(), []int{8, 9, 10})
+		if err != nil || n < 1 {
+			t.Error("PutManyContext should add at least 1 value", n, err)
+		}
+	}()
+	time.Sleep(5 * time.Millisecond)
+	q.Get()
+	wg.Wait()
+
+	// the context releases a blocked batch Put
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := q.PutManyContext(ctx, []int{99})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Error("err should be context.DeadlineExceeded", err)
+	}
+
+	// a closed queue hands out what is left even if it is less than min
+	q.Close()
+	dst := make([]int, 100)
+	n, err := q.GetManyContext(context.Background(), dst, 100, 100)
+	if err != nil || n != bqcap {
+		t.Error("GetManyContext should drain the queue", n, err)
+	}
+	_, err = q.GetManyContext(context.Background(), dst, 1, 1)
+	if !errors.Is(err, ErrClosed) {
+		t.Error("err should be ErrClosed", err)
+	}
+}
+
+// bounds below zero get nothing and don't fail
+func batch3(t *testing.T, q SynchronizedQueue[int]) {
+	q.Put(1)
+	dst := make([]int, 4)
+
+	for _, tc := range []struct {
+		name     string
+		min, max int
+	}{
+		{"max -1", 0, -1},
+		{"min and max -1", -1, -1},
+		{"min -5 max -3", -5, -3},
+		{"min 2 max -1", 2, -1},
+	} {
+		if n, err := q.GetMany(dst, tc.max); n != 0 || err != nil {
+			t.Error(tc.name, "GetMany should return 0 and no error", n, err)
+		}
+		if n, err := q.GetManyContext(context.Background(), dst, tc.min, tc.max); n != 0 || err != nil {
+			t.Error(tc.name, "GetManyContext should return 0 and no error", n, err)
+		}
+	}
+	if q.Len() != 1 {
+		t.Error("length should == 1", q.Len())
+	}
+}
+
+func batchAll(t *testing.T, factory func() SynchronizedQueue[int]) {
+	batch1(t, factory())
+	batch2(t, factory())
+	batch3(t, factory())
+}
+
+func TestChannelBatch(t *testing.T) {
+	batchAll(t, func() SynchronizedQueue[int] { return NewChannelQueueOf[int](bqcap) })
+}
+
+func TestCircularBatch(t *testing.T) {
+	batchAll(t, func() SynchronizedQueue[int] { return NewSyncCircularOf[int](bqcap) })
+}
+
+func TestListBatch(t *testing.T) {
+	batchAll(t, func() SynchronizedQueue[int] { return NewSyncListOf[int](bqcap) })
+}
+
+func TestNativeBatch(t *testing.T) {
+	batchAll(t, func() SynchronizedQueue[int] { return NewNativeQueueOf[int](bqcap) })
+}
+
+func TestMPMCBatch(t *testing.T) {
+	batchAll(t, func() SynchronizedQueue[int] { return NewMPMCQueueOf[int](bqcap) })
+}
+
+func TestGrowableBatch(t *testing.T) {
+	batchAll(t, func() SynchronizedQueue[int] { return NewSyncGrowableOf[int](bqcap) })
+}
@@ -1,6 +1,7 @@
 package queue
 
 import (
+	"fmt"
 	"sync"
 	"testing"
 )
@@ -10,47 +11,107 @@ import (
 // queue size
 var bqsize int = 20
 
-
-func b1(b *testing.B, q SynchronizedQueue) {
+func b1(b *testing.B, q SynchronizedQueue[interface{}]) {
 	// fill the queue with ints
-	for i:=0;i<q.Cap();i++ {
+	for i := 0; i < q.Cap(); i++ {
 		var x interface{}
 		x = i
 
 		q.Put(x)
 		//length should be == i at this point
-		if q.Len() != (i+1) {
-			b.Error("length should == i+1",q.Len(),i+1)
+		if q.Len() != (i + 1) {
+			b.Error("length should == i+1", q.Len(), i+1)
 		}
 	}
 
 	// remove all items
-	for i:=0;i<q.Cap();i++ {
-		v := q.Get().(int)
+	for i := 0; i < q.Cap(); i++ {
+		value, err := q.Get()
+		if err != nil {
+			b.Error(err)
+		}
+		v := value.(int)
 		if v != i {
-			b.Error("v should == i",v,i)
+			b.Error("v should == i", v, i)
 		}
 	}
 }
 
 func b2(b *testing.B, q *NativeIntQueue) {
 	// fill the queue with ints
-	for i:=0;i<q.Cap();i++ {
+	for i := 0; i < q.Cap(); i++ {
 		var x int
 		x = i
 
 		q.Put(x)
 		//length should be == i at this point
-		if q.Len() != (i+1) {
-			b.Error("length should == i+1",q.Len(),i+1)
+		if q.Len() != (i + 1) {
+			b.Error("length should == i+1", q.Len(), i+1)
+		}
+	}
+
+	// remove all items
+	for i := 0; i < q.Cap(); i++ {
+		v, err := q.Get()
+		if err != nil {
+			b.Error(err)
+		}
+		if v != i {
+			b.Error("v should == i", v, i)
+		}
+	}
+}
+
+func b3(b *testing.B, q SynchronizedQueue[int]) {
+	// fill the queue with ints, no boxing
+	for i := 0; i < q.Cap(); i++ {
+		q.Put(i)
+		//length should be == i at this point
+		if q.Len() != (i + 1) {
+			b.Error("length should == i+1", q.Len(), i+1)
+		}
+	}
+
+	// remove all items
+	for i := 0; i < q.Cap(); i++ {
+		v, err := q.Get()
+		if err != nil {
+			b.Error(err)
+		}
+		if v != i {
+			b.Error("v should == i", v, i)
 		}
 	}
+}
+
+func b4(b *testing.B, q SynchronizedQueue[int], values []int, dst []int) {
+	// fill the queue in one batch
+	n, err := q.PutMany(values)
+	if err != nil || n != q.Cap() {
+		b.Error("n should == capacity", n, err)
+	}
+
+	// remove all items in one batch
+	n, err = q.GetMany(dst, len(dst))
+	if err != nil || n != q.Cap() {
+		b.Error("n should == capacity", n, err)
+	}
+}
+
+func b5(b *testing.B, q *SPSCQueue[int]) {
+	// fill the queue with ints, no locks
+	for i := 0; i < q.Cap(); i++ {
+		q.Put(i)
+	}
 
 	// remove all items
-	for i:=0;i<q.Cap();i++ {
-		v := q.Get()
+	for i := 0; i < q.Cap(); i++ {
+		v, err := q.Get()
+		if err != nil {
+			b.Error(err)
+		}
 		if v != i {
-			b.Error("v should == i",v,i)
+			b.Error("v should == i", v, i)
 		}
 	}
 }
@@ -59,71 +120,231 @@ func b2(b *testing.B, q *NativeIntQueue) {
 
 func BenchmarkQueueChannelSync(b *testing.B) {
 	// using channel
-	for i:=0;i<b.N;i++ {
-		b1(b,NewChannelQueue(bqsize))
+	for i := 0; i < b.N; i++ {
+		b1(b, NewChannelQueue(bqsize))
 	}
 }
 
 func BenchmarkListSync(b *testing.B) {
 	// using condition variable queue
-	for i:=0;i<b.N;i++ {
-		b1(b,NewSyncList(bqsize))
+	for i := 0; i < b.N; i++ {
+		b1(b, NewSyncList(bqsize))
 	}
 }
 
 func BenchmarkCircularSync(b *testing.B) {
 	// using condition variable queue
-	for i:=0;i<b.N;i++ {
-		b1(b,NewSyncCircular(bqsize))
+	for i := 0; i < b.N; i++ {
+		b1(b, NewSyncCircular(bqsize))
 	}
 }
 
 func BenchmarkRingSync(b *testing.B) {
 	// using condition variable queue
-	for i:=0;i<b.N;i++ {
-		b1(b,NewSyncRing(bqsize))
+	for i := 0; i < b.N; i++ {
+		b1(b, NewSyncRing(bqsize))
 	}
 }
 
 func BenchmarkSliceSync(b *testing.B) {
 	// using condition variable queue
-	for i:=0;i<b.N;i++ {
-		b1(b,NewSyncSlice(bqsize))
+	for i := 0; i < b.N; i++ {
+		b1(b, NewSyncSlice(bqsize))
 	}
 }
 
 func BenchmarkQueueNativeSync(b *testing.B) {
 	// using condition variable queue
-	for i:=0;i<b.N;i++ {
-		b2(b,NewNativeQueue(bqsize))
+	for i := 0; i < b.N; i++ {
+		b2(b, NewNativeQueue(bqsize))
 	}
 }
 
-// Asynchronous benchmarks
+func BenchmarkCircularTypedSync(b *testing.B) {
+	// using condition variable queue with typed elements
+	for i := 0; i < b.N; i++ {
+		b3(b, NewSyncCircularOf[int](bqsize))
+	}
+}
+
+func BenchmarkQueueChannelTypedSync(b *testing.B) {
+	// using typed channel
+	for i := 0; i < b.N; i++ {
+		b3(b, NewChannelQueueOf[int](bqsize))
+	}
+}
+
+func BenchmarkCircularBatchSync(b *testing.B) {
+	// using condition variable queue with one lock per batch
+	values := make([]int, bqsize)
+	dst := make([]int, bqsize)
+	for i := 0; i < b.N; i++ {
+		b4(b, NewSyncCircularOf[int](bqsize), values, dst)
+	}
+}
+
+func BenchmarkQueueNativeBatchSync(b *testing.B) {
+	// using native queue with one lock per batch
+	values := make([]int, bqsize)
+	dst := make([]int, bqsize)
+	for i := 0; i < b.N; i++ {
+		b4(b, NewNativeQueueOf[int](bqsize), values, dst)
+	}
+}
+
+func BenchmarkSPSCSync(b *testing.B) {
+	// using the lock-free ring
+	for i := 0; i < b.N; i++ {
+		b5(b, NewSPSCQueueOf[int](bqsize))
+	}
+}
+
+// Contention benchmarks
+
+// contention moves b.N elements through the queue with n producers
+// and n consumers all hitting it at once
+func contention(b *testing.B, factory func() SynchronizedQueue[int]) {
+	for _, n := range []int{1, 4, 16, 64} {
+		b.Run(fmt.Sprintf("goroutines-%d", n), func(b *testing.B) {
+			var wg sync.WaitGroup
+
+			q := factory()
+			b.ResetTimer()
+
+			wg.Add(2 * n)
+			for g := 0; g < n; g++ {
+				// split b.N as evenly as possible
+				count := b.N / n
+				if g < b.N%n {
+					count++
+				}
+
+				go func() {
+					defer wg.Done()
+					for i := 0; i < count; i++ {
+						q.Put(i)
+					}
+				}()
+
+				go func() {
+					defer wg.Done()
+					for i := 0; i < count; i++ {
+						if _, err := q.Get(); err != nil {
+							b.Error(err)
+						}
+					}
+				}()
+			}
+			wg.Wait()
+		})
+	}
+}
+
+func BenchmarkQueueChannelContention(b *testing.B) {
+	// using channel
+	contention(b, func() SynchronizedQueue[int] { return NewChannelQueueOf[int](bqsize) })
+}
+
+func BenchmarkCircularContention(b *testing.B) {
+	// using condition variable queue
+	contention(b, func() SynchronizedQueue[int] { return NewSyncCircularOf[int](bqsize) })
+}
+
+func BenchmarkQueueNativeContention(b *testing.B) {
+	// using native queue
+	contention(b, func() SynchronizedQueue[int] { return NewNativeQueueOf[int](bqsize) })
+}
+
+func BenchmarkQueueMPMCContention(b *testing.B) {
+	// using the lock-free slots
+	contention(b, func() SynchronizedQueue[int] { return NewMPMCQueueOf[int](bqsize) })
+}
+
+// Work stealing benchmarks
 
+// number of workers in the pools
+const bworkers int = 4
+
+func BenchmarkSharedPool(b *testing.B) {
+	// workers sharing one condition variable queue
+	var wg sync.WaitGroup
+	var count int64
+	var mtx sync.Mutex
+
+	q := NewSyncGrowableOf[func()](Unbounded)
+	wg.Add(bworkers)
+	for w := 0; w < bworkers; w++ {
+		go func() {
+			defer wg.Done()
+			for {
+				task, err := q.Get()
+				if err != nil {
+					return
+				}
+				task()
+			}
+		}()
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		q.Put(func() {
+			mtx.Lock()
+			count++
+			mtx.Unlock()
+		})
+	}
+	q.Close()
+	wg.Wait()
+}
+
+func BenchmarkSchedulerSpawn(b *testing.B) {
+	// workers with their own deques, stealing when idle
+	var count int64
+	var mtx sync.Mutex
+
+	s := NewScheduler(bworkers)
+
+	b.ResetTimer()
+	s.Submit(func(w *Worker) {
+		for i := 0; i < b.N; i++ {
+			w.Spawn(func(w *Worker) {
+				mtx.Lock()
+				count++
+				mtx.Unlock()
+			})
+		}
+	})
+	s.Close()
+}
+
+// Asynchronous benchmarks
 
 // ==================
 // Asynchronous Tests
 // ==================
 
 // - blocking with no delays
-func producer1a(q SynchronizedQueue, wg *sync.WaitGroup) {
+func producer1a(q SynchronizedQueue[interface{}], wg *sync.WaitGroup) {
 	// fill the queue with ints
-	for i:=0;i<q.Cap();i++ {
+	for i := 0; i < q.Cap(); i++ {
 		q.Put(i)
 	}
 
 	wg.Done()
 }
 
-func consumer1a(q SynchronizedQueue, b *testing.B, wg *sync.WaitGroup)  {
+func consumer1a(q SynchronizedQueue[interface{}], b *testing.B, wg *sync.WaitGroup) {
 	// consume all items
-	for i:=0;i<q.Cap();i++ {
-		value := q.Get()
+	for i := 0; i < q.Cap(); i++ {
+		value, err := q.Get()
+		if err != nil {
+			b.Error(err)
+		}
 		// convert to int
 		v := value.(int)
 		if v != i {
-			b.Error("v should == i",v,i)
+			b.Error("v should == i", v, i)
 		}
 	}
 	wg.Done()
@@ -132,7 +353,7 @@ func consumer1a(q SynchronizedQueue, b *testing.B, wg *sync.WaitGroup)  {
 // - blocking with native ints, no delays
 func producer2a(q *NativeIntQueue, wg *sync.WaitGroup) {
 	// fill the queue with ints
-	for i:=0;i<q.Cap();i++ {
+	for i := 0; i < q.Cap(); i++ {
 		q.Put(i)
 	}
 	wg.Done()
@@ -140,22 +361,47 @@ func producer2a(q *NativeIntQueue, wg *sync.WaitGroup) {
 
 func consumer2a(q *NativeIntQueue, b *testing.B, wg *sync.WaitGroup) {
 	// consume all items
-	for i:=0;i<q.Cap();i++ {
-		v := q.Get()
+	for i := 0; i < q.Cap(); i++ {
+		v, err := q.Get()
+		if err != nil {
+			b.Error(err)
+		}
 		if v != i {
-			b.Error("v should == i",v,i)
+			b.Error("v should == i", v, i)
 		}
 	}
 	wg.Done()
 }
 
+// - single producer single consumer, no delays
+func producer3a(q *SPSCQueue[int], wg *sync.WaitGroup) {
+	// fill the queue with ints
+	for i := 0; i < q.Cap(); i++ {
+		q.Put(i)
+	}
+	wg.Done()
+}
+
+func consumer3a(q *SPSCQueue[int], b *testing.B, wg *sync.WaitGroup) {
+	// consume all items
+	for i := 0; i < q.Cap(); i++ {
+		v, err := q.Get()
+		if err != nil {
+			b.Error(err)
+		}
+		if v != i {
+			b.Error("v should == i", v, i)
+		}
+	}
+	wg.Done()
+}
 
-func asyncb1(b *testing.B, q SynchronizedQueue) {
+func asyncb1(b *testing.B, q SynchronizedQueue[interface{}]) {
 	var wg sync.WaitGroup
 
 	wg.Add(2)
-	go producer1a(q,&wg)
-	go consumer1a(q,b,&wg)
+	go producer1a(q, &wg)
+	go consumer1a(q, b, &wg)
 	wg.Wait()
 }
 
@@ -163,51 +409,65 @@ func asyncb2(b *testing.B, q *NativeIntQueue) {
 	var wg sync.WaitGroup
 
 	wg.Add(2)
-	go producer2a(q,&wg)
-	go consumer2a(q,b,&wg)
+	go producer2a(q, &wg)
+	go consumer2a(q, b, &wg)
 	wg.Wait()
 }
 
 func BenchmarkQueueChannelAsync(b *testing.B) {
 	// using channel
-	for i:=0;i<b.N;i++ {
-		asyncb1(b,NewChannelQueue(bqsize))
+	for i := 0; i < b.N; i++ {
+		asyncb1(b, NewChannelQueue(bqsize))
 	}
 }
 
 func BenchmarkListAsync(b *testing.B) {
 	// using condition variable queue
-	for i:=0;i<b.N;i++ {
-		asyncb1(b,NewSyncList(bqsize))
+	for i := 0; i < b.N; i++ {
+		asyncb1(b, NewSyncList(bqsize))
 	}
 }
 
 func BenchmarkCircularAsync(b *testing.B) {
 	// using condition variable queue
-	for i:=0;i<b.N;i++ {
-		asyncb1(b,NewSyncCircular(bqsize))
+	for i := 0; i < b.N; i++ {
+		asyncb1(b, NewSyncCircular(bqsize))
 	}
 }
 
 func BenchmarkRingAsync(b *testing.B) {
 	// using condition variable queue
-	for i:=0;i<b.N;i++ {
-		asyncb1(b,NewSyncRing(bqsize))
+	for i := 0; i < b.N; i++ {
+		asyncb1(b, NewSyncRing(bqsize))
 	}
 }
 
 func BenchmarkSliceAsync(b *testing.B) {
 	// using condition variable queue
-	for i:=0;i<b.N;i++ {
-		asyncb1(b,NewSyncSlice(bqsize))
+	for i := 0; i < b.N; i++ {
+		asyncb1(b, NewSyncSlice(bqsize))
 	}
 }
 
-
 func BenchmarkQueueNativeAsync(b *testing.B) {
 	// using condition variable queue
-	for i:=0;i<b.N;i++ {
-		asyncb2(b,NewNativeQueue(bqsize))
+	for i := 0; i < b.N; i++ {
+		asyncb2(b, NewNativeQueue(bqsize))
 	}
 }
 
+func asyncb3(b *testing.B, q *SPSCQueue[int]) {
+	var wg sync.WaitGroup
+
+	wg.Add(2)
+	go producer3a(q, &wg)
+	go consumer3a(q, b, &wg)
+	wg.Wait()
+}
+
+func BenchmarkSPSCAsync(b *testing.B) {
+	// using the lock-free ring
+	for i := 0; i < b.N; i++ {
+		asyncb3(b, NewSPSCQueueOf[int](bqsize))
+	}
+}
@@ -0,0 +1,127 @@
+package queue
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// test variables
+const clqsize int = 4
+
+// blocked Gets are all woken up by Close
+func close1(t *testing.T, q SynchronizedQueue[int]) {
+	var wg sync.WaitGroup
+
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := q.Get()
+			if !errors.Is(err, ErrClosed) {
+				t.Error("err should be ErrClosed", err)
+			}
+		}()
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	q.Close()
+	wg.Wait()
+}
+
+// blocked Puts are all woken up by Close
+func close2(t *testing.T, q SynchronizedQueue[int]) {
+	var wg sync.WaitGroup
+
+	for i := 0; i < q.Cap(); i++ {
+		q.Put(i)
+	}
+
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := q.Put(99)
+			if !errors.Is(err, ErrClosed) {
+				t.Error("err should be ErrClosed", err)
+			}
+		}()
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	q.Close()
+	wg.Wait()
+
+	// the elements put before Close are still there
+	if q.Len() != q.Cap() {
+		t.Error("length should == capacity", q.Len())
+	}
+}
+
+// Puts fail after Close and Gets drain the remaining elements
+func close3(t *testing.T, q SynchronizedQueue[int]) {
+	q.Put(1)
+	q.Put(2)
+	q.Close()
+
+	// calling it twice is fine
+	q.Close()
+
+	if err := q.Put(3); !errors.Is(err, ErrClosed) {
+		t.Error("Put err should be ErrClosed", err)
+	}
+	if err := q.TryPut(3); !errors.Is(err, ErrClosed) {
+		t.Error("TryPut err should be ErrClosed", err)
+	}
+
+	v, err := q.Get()
+	if err != nil || v != 1 {
+		t.Error("Get should return 1", v, err)
+	}
+	v, err = q.TryGet()
+	if err != nil || v != 2 {
+		t.Error("TryGet should return 2", v, err)
+	}
+
+	// now drained
+	if _, err = q.Get(); !errors.Is(err, ErrClosed) {
+		t.Error("Get err should be ErrClosed", err)
+	}
+	if _, err = q.TryGet(); !errors.Is(err, ErrClosed) {
+		t.Error("TryGet err should be ErrClosed", err)
+	}
+	if _, err = q.GetTimeout(time.Second); !errors.Is(err, ErrClosed) {
+		t.Error("GetTimeout err should be ErrClosed", err)
+	}
+}
+
+func closeAll(t *testing.T, factory func() SynchronizedQueue[int]) {
+	close1(t, factory())
+	close2(t, factory())
+	close3(t, factory())
+}
+
+func TestChannelClose(t *testing.T) {
+	closeAll(t, func() SynchronizedQueue[int] { return NewChannelQueueOf[int](clqsize) })
+}
+
+func TestCircularClose(t *testing.T) {
+	closeAll(t, func() SynchronizedQueue[int] { return NewSyncCircularOf[int](clqsize) })
+}
+
+func TestSliceClose(t *testing.T) {
+	closeAll(t, func() SynchronizedQueue[int] { return NewSyncSliceOf[int](clqsize) })
+}
+
+func TestNativeClose(t *testing.T) {
+	closeAll(t, func() SynchronizedQueue[int] { return NewNativeQueueOf[int](clqsize) })
+}
+
+func TestMPMCClose(t *testing.T) {
+	closeAll(t, func() SynchronizedQueue[int] { return NewMPMCQueueOf[int](clqsize) })
+}
+
+func TestGrowableClose(t *testing.T) {
+	closeAll(t, func() SynchronizedQueue[int] { return NewSyncGrowableOf[int](clqsize) })
+}
@@ -0,0 +1,50 @@
+package queue
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+)
+
+// Codec turns elements of type T into bytes and back,
+// for queues that keep their elements outside of memory
+type Codec[T any] interface {
+	Encode(value T) ([]byte, error)
+	Decode(data []byte) (T, error)
+}
+
+// GobCodec encodes elements with encoding/gob. the concrete types
+// stored in an interface{} queue must be registered with gob.Register
+type GobCodec[T any] struct{}
+
+func (GobCodec[T]) Encode(value T) ([]byte, error) {
+	var buf bytes.Buffer
+
+	// encode through a pointer so an interface{} keeps its concrete type
+	if err := gob.NewEncoder(&buf).Encode(&value); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (GobCodec[T]) Decode(data []byte) (T, error) {
+	var value T
+
+	err := gob.NewDecoder(bytes.NewReader(data)).Decode(&value)
+	return value, err
+}
+
+// JSONCodec encodes elements with encoding/json. an interface{} queue
+// gets back the generic JSON types, e.g. float64 for numbers
+type JSONCodec[T any] struct{}
+
+func (JSONCodec[T]) Encode(value T) ([]byte, error) {
+	return json.Marshal(value)
+}
+
+func (JSONCodec[T]) Decode(data []byte) (T, error) {
+	var value T
+
+	err := json.Unmarshal(data, &value)
+	return value, err
+}
@@ -0,0 +1,177 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// test variables
+const cqsize int = 4
+
+// test the context aware Put and Get of a SynchronizedQueue
+func context1(t *testing.T, q SynchronizedQueue[int]) {
+	// an already cancelled context fails a Get on an empty queue
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := q.GetContext(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Error("err should be context.Canceled", err)
+	}
+
+	// a blocked Get is released when the context is cancelled
+	ctx, cancel = context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	_, err = q.GetContext(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Error("err should be context.Canceled", err)
+	}
+
+	// fill the queue, a live context doesn't get in the way
+	for i := 0; i < q.Cap(); i++ {
+		err = q.PutContext(context.Background(), i)
+		if err != nil {
+			t.Error(err)
+		}
+	}
+
+	// a blocked Put is released when the deadline passes
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err = q.PutContext(ctx, 99)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Error("err should be context.DeadlineExceeded", err)
+	}
+	// check length is unchanged
+	if q.Len() != q.Cap() {
+		t.Error("length should == capacity", q.Len())
+	}
+
+	// drain the queue in order
+	for i := 0; i < q.Cap(); i++ {
+		v, err := q.GetContext(context.Background())
+		if err != nil {
+			t.Error(err)
+		}
+		if v != i {
+			t.Error("v should == i", v, i)
+		}
+	}
+}
+
+// a blocked Put is woken by a Get even though its context is live
+func context2(t *testing.T, q SynchronizedQueue[int]) {
+	for i := 0; i < q.Cap(); i++ {
+		q.Put(i)
+	}
+
+	done := make(chan error)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	go func() {
+		done <- q.PutContext(ctx, q.Cap())
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	q.Get()
+	err := <-done
+	if err != nil {
+		t.Error(err)
+	}
+	if q.Len() != q.Cap() {
+		t.Error("length should == capacity", q.Len())
+	}
+}
+
+// test the timed Put and Get of a SynchronizedQueue
+func timeout1(t *testing.T, q SynchronizedQueue[int]) {
+	// Get on an empty queue times out after roughly d
+	start := time.Now()
+	_, err := q.GetTimeout(20 * time.Millisecond)
+	if !errors.Is(err, ErrTimeout) {
+		t.Error("err should be ErrTimeout", err)
+	}
+	if time.Since(start) < 20*time.Millisecond {
+		t.Error("GetTimeout returned early", time.Since(start))
+	}
+
+	// fill the queue
+	for i := 0; i < q.Cap(); i++ {
+		err = q.PutTimeout(i, 20*time.Millisecond)
+		if err != nil {
+			t.Error(err)
+		}
+	}
+
+	// Put on a full queue times out
+	err = q.PutTimeout(99, 20*time.Millisecond)
+	if !errors.Is(err, ErrTimeout) {
+		t.Error("err should be ErrTimeout", err)
+	}
+
+	// an element arriving within the timeout is returned
+	v, err := q.GetTimeout(20 * time.Millisecond)
+	if err != nil {
+		t.Error(err)
+	}
+	if v != 0 {
+		t.Error("v should == 0", v)
+	}
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		q.Get()
+	}()
+	err = q.PutTimeout(99, time.Second)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestChannelContext(t *testing.T) {
+	context1(t, NewChannelQueueOf[int](cqsize))
+	context2(t, NewChannelQueueOf[int](cqsize))
+}
+
+func TestChannelTimeout(t *testing.T) {
+	timeout1(t, NewChannelQueueOf[int](cqsize))
+}
+
+func TestCircularContext(t *testing.T) {
+	context1(t, NewSyncCircularOf[int](cqsize))
+	context2(t, NewSyncCircularOf[int](cqsize))
+}
+
+func TestCircularTimeout(t *testing.T) {
+	timeout1(t, NewSyncCircularOf[int](cqsize))
+}
+
+func TestListContext(t *testing.T) {
+	context1(t, NewSyncListOf[int](cqsize))
+	context2(t, NewSyncListOf[int](cqsize))
+}
+
+func TestListTimeout(t *testing.T) {
+	timeout1(t, NewSyncListOf[int](cqsize))
+}
+
+func TestNativeContext(t *testing.T) {
+	context1(t, NewNativeQueueOf[int](cqsize))
+	context2(t, NewNativeQueueOf[int](cqsize))
+}
+
+func TestNativeTimeout(t *testing.T) {
+	timeout1(t, NewNativeQueueOf[int](cqsize))
+}
+
+func TestMPMCContext(t *testing.T) {
+	context1(t, NewMPMCQueueOf[int](cqsize))
+	context2(t, NewMPMCQueueOf[int](cqsize))
+}
+
+func TestMPMCTimeout(t *testing.T) {
+	timeout1(t, NewMPMCQueueOf[int](cqsize))
+}
@@ -0,0 +1,176 @@
+package queue
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+// test variables
+const dlqsize int = 4
+
+// an item that fails maxAttempts times goes to the dead-letter queue
+func deadletter1(t *testing.T, q *ReliableQueue[int], dead SynchronizedQueue[DeadLetter[int]]) {
+	q.Put(1)
+
+	// 1 fails by Nack, then by timeout, then by Nack
+	d, _ := q.Receive()
+	q.Nack(d.Tag(), errTest)
+	q.Receive()
+	d, err := q.ReceiveTimeout(time.Second)
+	if err != nil || d.Value() != 1 || d.Attempts() != 3 || !errors.Is(d.LastError(), ErrTimeout) {
+		t.Error("1 should come back after its timeout", d, err)
+	}
+	if dead.Len() != 0 {
+		t.Error("nothing should be dead yet", dead.Len())
+	}
+	q.Nack(d.Tag(), errTest)
+
+	letters := q.DeadLetters()
+	if len(letters) != 1 || letters[0].Value() != 1 || letters[0].Attempts() != 3 || !errors.Is(letters[0].Reason(), errTest) {
+		t.Error("1 should be dead after 3 attempts", letters)
+	}
+	if _, err := q.TryReceive(); !errors.Is(err, ErrEmpty) {
+		t.Error("err should be ErrEmpty", err)
+	}
+
+	// 2 times out three times
+	q.Put(2)
+	for i := 0; i < 3; i++ {
+		q.Receive()
+	}
+	if _, err := q.ReceiveTimeout(100 * time.Millisecond); !errors.Is(err, ErrTimeout) {
+		t.Error("err should be ErrTimeout", err)
+	}
+	letters = q.DeadLetters()
+	if len(letters) != 2 || letters[1].Value() != 2 || !errors.Is(letters[1].Reason(), ErrTimeout) {
+		t.Error("2 should be dead of timeouts", letters)
+	}
+	if q.InFlight() != 0 {
+		t.Error("nothing should be in flight", q.InFlight())
+	}
+}
+
+// dead letters can be replayed and purged
+func deadletter2(t *testing.T, q *ReliableQueue[int], dead SynchronizedQueue[DeadLetter[int]]) {
+	for i := 0; i < dlqsize; i++ {
+		q.Put(i)
+		d, _ := q.Receive()
+		q.Nack(d.Tag(), errTest)
+	}
+	if dead.Len() != dlqsize {
+		t.Error("every item should be dead", dead.Len())
+	}
+
+	// the dead-letter queue is full, the next one stays
+	q.Put(99)
+	d, _ := q.Receive()
+	q.Nack(d.Tag(), errTest)
+	d, err := q.TryReceive()
+	if err != nil || d.Value() != 99 || d.Attempts() != 2 {
+		t.Error("99 should stay when there's no room for it", d, err)
+	}
+	q.Ack(d.Tag())
+
+	// a negative count replays nothing
+	if n, err := q.Replay(-3); n != 0 || err != nil || dead.Len() != dlqsize {
+		t.Error("Replay(-3) should move nothing", n, err, dead.Len())
+	}
+
+	// replay two, they start over
+	if n, err := q.Replay(2); n != 2 || err != nil {
+		t.Error("Replay should move 2", n, err)
+	}
+	for i := 0; i < 2; i++ {
+		d, err := q.TryReceive()
+		if err != nil || d.Value() != i || d.Attempts() != 1 || d.LastError() != nil {
+			t.Error("a replayed item should start over", d, err)
+		}
+		q.Ack(d.Tag())
+	}
+
+	if n := q.Purge(); n != dlqsize-2 {
+		t.Error("Purge should drop the rest", n)
+	}
+	if len(q.DeadLetters()) != 0 {
+		t.Error("the dead letters should be gone", q.DeadLetters())
+	}
+	if n, err := q.Replay(1); n != 0 || err != nil {
+		t.Error("there should be nothing to replay", n, err)
+	}
+}
+
+// yieldQueue lets other goroutines in after a TryPeek
+type yieldQueue[T any] struct {
+	SynchronizedQueue[T]
+}
+
+func (q yieldQueue[T]) TryPeek() (T, error) {
+	v, err := q.SynchronizedQueue.TryPeek()
+	runtime.Gosched()
+	return v, err
+}
+
+// concurrent Replays move each dead letter exactly once
+func deadletter3(t *testing.T, replayers int) {
+	const count = 10
+	dead := yieldQueue[DeadLetter[int]]{NewSyncCircularOf[DeadLetter[int]](count)}
+	q := NewReliableQueueDeadLetterOf(NewSyncCircularOf[int](count), time.Second, 1, dead)
+
+	for i := 0; i < count; i++ {
+		q.Put(i)
+		d, _ := q.Receive()
+		q.Nack(d.Tag(), errTest)
+	}
+
+	var wg sync.WaitGroup
+	for r := 0; r < replayers; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				n, err := q.Replay(1)
+				if err != nil {
+					t.Error("Replay should not fail", err)
+				}
+				if n == 0 {
+					return
+				}
+			}
+		}()
+	}
+	// and look while they work
+	for len(q.DeadLetters()) > 0 {
+		runtime.Gosched()
+	}
+	wg.Wait()
+
+	for i := 0; i < count; i++ {
+		d, err := q.TryReceive()
+		if err != nil || d.Value() != i {
+			t.Error("each item should be replayed once, in order", d, i, err)
+		}
+	}
+	if q.Len() != 0 || q.Purge() != 0 {
+		t.Error("nothing should be left", q.Len())
+	}
+}
+
+// DEAD LETTERS
+func TestDeadLetter(t *testing.T) {
+	dead := NewSyncCircularOf[DeadLetter[int]](dlqsize)
+	deadletter1(t, NewReliableQueueDeadLetterOf(NewSyncCircularOf[int](dlqsize), 20*time.Millisecond, 3, dead), dead)
+
+	dead = NewSyncCircularOf[DeadLetter[int]](dlqsize)
+	deadletter2(t, NewReliableQueueDeadLetterOf(NewSyncCircularOf[int](dlqsize), time.Second, 1, dead), dead)
+
+	deadletter3(t, 4)
+
+	// without a dead-letter queue items are retried forever
+	q := NewReliableQueueOf(NewSyncCircularOf[int](dlqsize), time.Second)
+	if len(q.DeadLetters()) != 0 || q.Purge() != 0 {
+		t.Error("there should be no dead letters")
+	}
+}
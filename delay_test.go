@@ -0,0 +1,106 @@
+package queue
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// test variables
+const dqsize int = 4
+
+// elements come out in the order they are due, not the order put
+func delay1(t *testing.T, q *DelayQueue[int]) {
+	now := time.Now()
+	q.PutAt(3, now.Add(30*time.Millisecond))
+	q.PutAt(1, now.Add(10*time.Millisecond))
+	q.PutAt(2, now.Add(20*time.Millisecond))
+
+	// nothing is due yet
+	if _, err := q.TryGet(); !errors.Is(err, ErrEmpty) {
+		t.Error("err should be ErrEmpty", err)
+	}
+	if _, err := q.TryPeek(); !errors.Is(err, ErrEmpty) {
+		t.Error("err should be ErrEmpty", err)
+	}
+	if len(q.PeekN(3)) != 0 {
+		t.Error("PeekN should be empty")
+	}
+	if q.Len() != 3 {
+		t.Error("length should == 3", q.Len())
+	}
+
+	// Get sleeps until each one is due
+	for i := 1; i <= 3; i++ {
+		v, err := q.Get()
+		if err != nil || v != i {
+			t.Error("v should == i", v, i, err)
+		}
+		if early := now.Add(time.Duration(i) * 10 * time.Millisecond).Sub(time.Now()); early > 0 {
+			t.Error("Get returned early", early)
+		}
+	}
+}
+
+// a Get waiting for a late element is re-armed by an earlier one
+func delay2(t *testing.T, q *DelayQueue[int]) {
+	q.PutAfter(2, time.Second)
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		q.PutAfter(1, 10*time.Millisecond)
+	}()
+
+	start := time.Now()
+	v, err := q.Get()
+	if err != nil || v != 1 {
+		t.Error("Get should return 1", v, err)
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		t.Error("Get waited for the late element", time.Since(start))
+	}
+}
+
+// the bound counts pending elements and Close lets them drain
+func delay3(t *testing.T, q *DelayQueue[int]) {
+	for i := 0; i < q.Cap(); i++ {
+		q.PutAfter(i, time.Duration(i)*5*time.Millisecond)
+	}
+	if err := q.TryPutAfter(99, 0); !errors.Is(err, ErrFull) {
+		t.Error("err should be ErrFull", err)
+	}
+
+	q.Close()
+	if err := q.PutAfter(99, 0); !errors.Is(err, ErrClosed) {
+		t.Error("err should be ErrClosed", err)
+	}
+
+	for i := 0; i < q.Cap(); i++ {
+		v, err := q.Get()
+		if err != nil || v != i {
+			t.Error("v should == i", v, i, err)
+		}
+	}
+	if _, err := q.Get(); !errors.Is(err, ErrClosed) {
+		t.Error("err should be ErrClosed", err)
+	}
+}
+
+// DELAY QUEUE
+func TestDelay(t *testing.T) {
+	delay1(t, NewDelayQueueOf[int](dqsize))
+	delay2(t, NewDelayQueueOf[int](dqsize))
+	delay3(t, NewDelayQueueOf[int](dqsize))
+}
+
+// elements put without a delay behave like any SynchronizedQueue
+func TestDelaySync(t *testing.T) {
+	sync1(t, NewDelayQueue(sqsize))
+	sync3(t, NewDelayQueueOf[int](sqsize))
+	closeAll(t, func() SynchronizedQueue[int] { return NewDelayQueueOf[int](clqsize) })
+	batchAll(t, func() SynchronizedQueue[int] { return NewDelayQueueOf[int](bqcap) })
+	peekAll(t, func() SynchronizedQueue[int] { return NewDelayQueueOf[int](pkqsize) })
+	context1(t, NewDelayQueueOf[int](cqsize))
+	context2(t, NewDelayQueueOf[int](cqsize))
+	timeout1(t, NewDelayQueueOf[int](cqsize))
+}
@@ -0,0 +1,145 @@
+package queue
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// test variables
+const dqcap int = 4
+
+// both ends of the plain deque
+func deque1(t *testing.T, q *CircularDeque[int]) {
+	// 1 0 2 3, pushed from both ends across the wrap
+	q.Push(2)
+	q.PushFront(1)
+	q.PushFront(0)
+	q.Push(3)
+	if err := q.PushFront(9); !errors.Is(err, ErrFull) {
+		t.Error("err should be ErrFull", err)
+	}
+
+	for i, want := range []int{0, 1, 2, 3} {
+		if v := q.PeekN(q.Len())[i]; v != want {
+			t.Error("PeekN should return want", v, want)
+		}
+	}
+	if v, err := q.PeekBack(); err != nil || v != 3 {
+		t.Error("PeekBack should return 3", v, err)
+	}
+
+	if v, _ := q.PopBack(); v != 3 {
+		t.Error("PopBack should return 3", v)
+	}
+	if v, _ := q.Pop(); v != 0 {
+		t.Error("Pop should return 0", v)
+	}
+	if v, _ := q.PopBack(); v != 2 {
+		t.Error("PopBack should return 2", v)
+	}
+	if v, _ := q.PopBack(); v != 1 {
+		t.Error("PopBack should return 1", v)
+	}
+	if _, err := q.PopBack(); !errors.Is(err, ErrEmpty) {
+		t.Error("err should be ErrEmpty", err)
+	}
+	if _, err := q.PeekBack(); !errors.Is(err, ErrEmpty) {
+		t.Error("err should be ErrEmpty", err)
+	}
+}
+
+// an element put back at the front is got next
+func deque2(t *testing.T, q *Deque[int]) {
+	for i := 0; i < 3; i++ {
+		q.PushBack(i)
+	}
+	v, _ := q.PopFront()
+	q.TryPushFront(v)
+
+	if v, _ := q.TryPopFront(); v != 0 {
+		t.Error("TryPopFront should return 0", v)
+	}
+	if v, _ := q.TryPeekBack(); v != 2 {
+		t.Error("TryPeekBack should return 2", v)
+	}
+	if v, _ := q.TryPopBack(); v != 2 {
+		t.Error("TryPopBack should return 2", v)
+	}
+	if v, _ := q.PopBack(); v != 1 {
+		t.Error("PopBack should return 1", v)
+	}
+	if _, err := q.TryPopBack(); !errors.Is(err, ErrEmpty) {
+		t.Error("err should be ErrEmpty", err)
+	}
+
+	q.Close()
+	if err := q.TryPushFront(0); !errors.Is(err, ErrClosed) {
+		t.Error("err should be ErrClosed", err)
+	}
+	if _, err := q.TryPopBack(); !errors.Is(err, ErrClosed) {
+		t.Error("err should be ErrClosed", err)
+	}
+	if _, err := q.TryPeekBack(); !errors.Is(err, ErrClosed) {
+		t.Error("err should be ErrClosed", err)
+	}
+}
+
+// a PopBack blocked on an empty deque is woken by a PushFront
+// and a PushFront blocked on a full one is woken by a PopBack
+func deque3(t *testing.T, q *Deque[int]) {
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		v, err := q.PopBack()
+		if err != nil || v != 1 {
+			t.Error("PopBack should return 1", v, err)
+		}
+	}()
+	time.Sleep(10 * time.Millisecond)
+	q.PushFront(1)
+	wg.Wait()
+
+	for i := 0; i < q.Cap(); i++ {
+		q.PushBack(i)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := q.PushFront(-1); err != nil {
+			t.Error("PushFront should not fail", err)
+		}
+	}()
+	time.Sleep(10 * time.Millisecond)
+	if v, _ := q.PopBack(); v != q.Cap()-1 {
+		t.Error("PopBack should return the last", v)
+	}
+	wg.Wait()
+
+	if v, _ := q.PopFront(); v != -1 {
+		t.Error("PopFront should return -1", v)
+	}
+}
+
+// DEQUE
+func TestDeque(t *testing.T) {
+	deque1(t, NewCircularDequeOf[int](dqcap))
+	deque2(t, NewDequeOf[int](dqcap))
+	deque3(t, NewDequeOf[int](dqcap))
+}
+
+// used from one end at each side it is any SynchronizedQueue
+func TestDequeSync(t *testing.T) {
+	queue1(t, NewCircularDequeOf[int](sqsize))
+	sync1(t, NewDeque(sqsize))
+	sync3(t, NewDequeOf[int](sqsize))
+	closeAll(t, func() SynchronizedQueue[int] { return NewDequeOf[int](clqsize) })
+	batchAll(t, func() SynchronizedQueue[int] { return NewDequeOf[int](bqcap) })
+	peekAll(t, func() SynchronizedQueue[int] { return NewDequeOf[int](pkqsize) })
+	context1(t, NewDequeOf[int](cqsize))
+	context2(t, NewDequeOf[int](cqsize))
+	timeout1(t, NewDequeOf[int](cqsize))
+}
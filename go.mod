@@ -1,3 +1,3 @@
 module dmh2000.xyz/queue
 
-go 1.15
+go 1.21
@@ -0,0 +1,20 @@
+//go:build !unix
+
+package queue
+
+import (
+	"errors"
+	"os"
+)
+
+var errNoMmap = errors.New("memory mapped queues are not supported on this platform")
+
+// mmap maps size bytes of file into memory, shared with the file
+func mmap(file *os.File, size int) ([]byte, error) {
+	return nil, errNoMmap
+}
+
+// munmap releases a mapping made by mmap
+func munmap(data []byte) error {
+	return errNoMmap
+}
@@ -0,0 +1,171 @@
+//go:build unix
+
+package queue
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// test variables
+const mmqsize int = 8
+
+// a fixed-size record
+type sample struct {
+	ID    uint32
+	Value float64
+	Valid bool
+}
+
+// what is in the queue is there when the file is opened again
+func mmap1(t *testing.T, path string) {
+	q, err := OpenMmapQueueOf[sample](path, mmqsize, nil)
+	if err != nil {
+		t.Fatal("open should not fail", err)
+	}
+	// go round the ring more than once
+	for i := 0; i < 2*mmqsize; i++ {
+		if err := q.Push(sample{uint32(i), float64(i) / 2, i%2 == 0}); err != nil {
+			t.Error("Push should not fail", err)
+		}
+		if i >= 3 {
+			q.Pop()
+		}
+	}
+	if err := q.Sync(); err != nil {
+		t.Error("Sync should not fail", err)
+	}
+	q.Close()
+	if _, err := q.Pop(); !errors.Is(err, ErrClosed) {
+		t.Error("err should be ErrClosed", err)
+	}
+
+	q, err = OpenMmapQueueOf[sample](path, mmqsize, nil)
+	if err != nil {
+		t.Fatal("reopen should not fail", err)
+	}
+	defer q.Close()
+	if q.Len() != 3 {
+		t.Error("length should == 3", q.Len())
+	}
+	for i := 2*mmqsize - 3; i < 2*mmqsize; i++ {
+		v, err := q.Pop()
+		if err != nil || v != (sample{uint32(i), float64(i) / 2, i%2 == 0}) {
+			t.Error("v should == i", v, i, err)
+		}
+	}
+}
+
+// a bad header is caught on open
+func mmap2(t *testing.T, dir string) {
+	path := filepath.Join(dir, "ints")
+	q, err := OpenMmapQueueOf[int](path, mmqsize, IntRecord{})
+	if err != nil {
+		t.Fatal("open should not fail", err)
+	}
+	q.Push(1)
+	q.Push(2)
+	q.Close()
+
+	// the other size is refused
+	if _, err := OpenMmapQueueOf[int](path, 2*mmqsize, IntRecord{}); err == nil {
+		t.Error("a different capacity should fail")
+	}
+	if _, err := OpenMmapQueueOf[int](filepath.Join(dir, "bad"), mmqsize, nil); !errors.Is(err, ErrType) {
+		t.Error("err should be ErrType", err)
+	}
+
+	// tearing the newer header, the second after three writes,
+	// falls back to the older one
+	data, _ := os.ReadFile(path)
+	data[mmapHeader+32] ^= 0xff
+	os.WriteFile(path, data, 0o644)
+	q, err = OpenMmapQueueOf[int](path, mmqsize, IntRecord{})
+	if err != nil {
+		t.Fatal("open should fall back to the other header", err)
+	}
+	if v, _ := q.Peek(); v != 1 || q.Len() != 1 {
+		t.Error("the older header should hold 1 element", v, q.Len())
+	}
+	q.Close()
+
+	// tearing both is an error
+	data, _ = os.ReadFile(path)
+	data[32] ^= 0xff
+	os.WriteFile(path, data, 0o644)
+	if _, err := OpenMmapQueueOf[int](path, mmqsize, IntRecord{}); !errors.Is(err, ErrCorrupt) {
+		t.Error("err should be ErrCorrupt", err)
+	}
+}
+
+// MMAP QUEUE
+// a record that can't be encoded isn't pushed
+type positiveRecord struct{ IntRecord }
+
+func (r positiveRecord) Put(dst []byte, value int) error {
+	if value < 0 {
+		return ErrType
+	}
+	return r.IntRecord.Put(dst, value)
+}
+
+func mmap3(t *testing.T, path string) {
+	if err := (BinaryRecord[sample]{}).Put(make([]byte, 4), sample{}); err == nil {
+		t.Error("a record too short for the value should fail")
+	}
+
+	q, err := OpenMmapQueueOf[int](path, mmqsize, positiveRecord{})
+	if err != nil {
+		t.Fatal("open should not fail", err)
+	}
+	defer q.Close()
+
+	q.Push(1)
+	if err := q.Push(-1); !errors.Is(err, ErrType) {
+		t.Error("err should be ErrType", err)
+	}
+	q.Push(2)
+	if q.Len() != 2 {
+		t.Error("length should == 2", q.Len())
+	}
+	for _, want := range []int{1, 2} {
+		if v, err := q.Pop(); err != nil || v != want {
+			t.Error("v should == want", v, want, err)
+		}
+	}
+}
+
+func TestMmap(t *testing.T) {
+	mmap1(t, filepath.Join(t.TempDir(), "samples"))
+	mmap2(t, t.TempDir())
+	mmap3(t, filepath.Join(t.TempDir(), "positive"))
+}
+
+// the typed queue behaves like the native one
+func TestMmapSync(t *testing.T) {
+	open := func(cap int) *MmapIntQueue {
+		q, err := OpenMmapIntQueue(filepath.Join(t.TempDir(), "q"), cap)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { q.Unmap() })
+		return q
+	}
+
+	mq, err := OpenMmapQueueOf[int](filepath.Join(t.TempDir(), "q"), sqsize, IntRecord{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mq.Close()
+
+	queue1(t, mq)
+	sync3(t, open(sqsize))
+	closeAll(t, func() SynchronizedQueue[int] { return open(clqsize) })
+	batchAll(t, func() SynchronizedQueue[int] { return open(bqcap) })
+	peekAll(t, func() SynchronizedQueue[int] { return open(pkqsize) })
+	context1(t, open(cqsize))
+	context2(t, open(cqsize))
+	timeout1(t, open(cqsize))
+}
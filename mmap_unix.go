@@ -0,0 +1,18 @@
+//go:build unix
+
+package queue
+
+import (
+	"os"
+	"syscall"
+)
+
+// mmap maps size bytes of file into memory, shared with the file
+func mmap(file *os.File, size int) ([]byte, error) {
+	return syscall.Mmap(int(file.Fd()), 0, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+}
+
+// munmap releases a mapping made by mmap
+func munmap(data []byte) error {
+	return syscall.Munmap(data)
+}
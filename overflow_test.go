@@ -0,0 +1,167 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// test variables
+const oqsize int = 4
+
+// a full queue evicts the oldest element
+func overflow1(t *testing.T, q *SynchronizedQueueImpl[int], evicted *[]int) {
+	for i := 0; i < 10; i++ {
+		// neither blocks
+		var err error
+		if i%2 == 0 {
+			err = q.Put(i)
+		} else {
+			err = q.TryPut(i)
+		}
+		if err != nil {
+			t.Error(err)
+		}
+	}
+
+	// the newest ones are left
+	for i := 6; i < 10; i++ {
+		v, err := q.TryGet()
+		if err != nil || v != i {
+			t.Error("v should == i", v, i, err)
+		}
+	}
+
+	if q.Dropped() != 6 || len(*evicted) != 6 {
+		t.Error("6 elements should have been dropped", q.Dropped(), *evicted)
+	}
+	for i, v := range *evicted {
+		if v != i {
+			t.Error("evicted should be in order", *evicted)
+		}
+	}
+
+	// a batch evicts too
+	n, err := q.PutMany([]int{10, 11, 12, 13, 14, 15})
+	if err != nil || n != 6 {
+		t.Error("PutMany should add all values", n, err)
+	}
+	values := q.PeekN(oqsize)
+	if len(values) != oqsize || values[0] != 12 || values[3] != 15 {
+		t.Error("the newest values should be left", values)
+	}
+}
+
+// a full queue discards the new element
+func overflow2(t *testing.T, q *SynchronizedQueueImpl[int], evicted *[]int) {
+	for i := 0; i < 10; i++ {
+		if err := q.Put(i); err != nil {
+			t.Error(err)
+		}
+	}
+
+	// the oldest ones are left
+	for i := 0; i < oqsize; i++ {
+		v, err := q.TryGet()
+		if err != nil || v != i {
+			t.Error("v should == i", v, i, err)
+		}
+	}
+
+	if q.Dropped() != 6 || len(*evicted) != 6 || (*evicted)[0] != oqsize {
+		t.Error("6 elements should have been dropped", q.Dropped(), *evicted)
+	}
+}
+
+// a full queue fails a Put right away
+func overflow3(t *testing.T, q *SynchronizedQueueImpl[int]) {
+	for i := 0; i < q.Cap(); i++ {
+		q.Put(i)
+	}
+
+	start := time.Now()
+	if err := q.Put(99); !errors.Is(err, ErrFull) {
+		t.Error("err should be ErrFull", err)
+	}
+	if _, err := q.PutManyContext(context.Background(), []int{99}); !errors.Is(err, ErrFull) {
+		t.Error("err should be ErrFull", err)
+	}
+	if time.Since(start) > 100*time.Millisecond {
+		t.Error("Put should not block", time.Since(start))
+	}
+	if q.Dropped() != 0 {
+		t.Error("nothing should have been dropped", q.Dropped())
+	}
+}
+
+// SYNCHRONIZED QUEUE overflow policies
+func TestOverflowSync(t *testing.T) {
+	var evicted []int
+	record := func(v int) { evicted = append(evicted, v) }
+
+	overflow1(t, NewSyncCircularOverflowOf(oqsize, OverflowDropOldest, record), &evicted)
+
+	evicted = nil
+	overflow1(t, NewSynchronizedQueueOverflowOf(NewListQueueOf[int](oqsize), OverflowDropOldest, record), &evicted)
+
+	evicted = nil
+	overflow2(t, NewSyncCircularOverflowOf(oqsize, OverflowDropNewest, record), &evicted)
+
+	overflow3(t, NewSyncCircularOverflowOf[int](oqsize, OverflowReject, nil))
+
+	// a backend with a policy of its own keeps it when wrapped
+	wrap := func(policy OverflowPolicy, evict func(int)) *SynchronizedQueueImpl[int] {
+		return NewSynchronizedQueueOf(NewCircularQueueOverflowOf(oqsize, policy, evict)).(*SynchronizedQueueImpl[int])
+	}
+
+	evicted = nil
+	overflow1(t, wrap(OverflowDropOldest, record), &evicted)
+
+	evicted = nil
+	overflow2(t, wrap(OverflowDropNewest, record), &evicted)
+
+	overflow3(t, wrap(OverflowReject, nil))
+
+	// Block is the default behavior
+	closeAll(t, func() SynchronizedQueue[int] {
+		return NewSyncCircularOverflowOf[int](clqsize, OverflowBlock, nil)
+	})
+}
+
+// CIRCULAR QUEUE as an overwriting ring
+func TestOverflowCircular(t *testing.T) {
+	var evicted []int
+	q := NewCircularQueueOverflowOf(oqsize, OverflowDropOldest, func(v int) { evicted = append(evicted, v) })
+
+	for i := 0; i < 10; i++ {
+		if err := q.Push(i); err != nil {
+			t.Error(err)
+		}
+	}
+	if q.Len() != oqsize {
+		t.Error("length should == oqsize", q.Len())
+	}
+	for i := 6; i < 10; i++ {
+		v, err := q.Pop()
+		if err != nil || v != i {
+			t.Error("v should == i", v, i, err)
+		}
+	}
+	if q.(*CircularQueue[int]).Dropped() != 6 || len(evicted) != 6 {
+		t.Error("6 elements should have been dropped", evicted)
+	}
+
+	// the newest element is discarded
+	q = NewCircularQueueOverflowOf[int](oqsize, OverflowDropNewest, nil)
+	for i := 0; i < 10; i++ {
+		q.Push(i)
+	}
+	if v, _ := q.Peek(); v != 0 {
+		t.Error("the oldest should be kept", v)
+	}
+
+	// a plain queue can't block, it rejects
+	q = NewCircularQueueOverflowOf[int](oqsize, OverflowBlock, nil)
+	queue1(t, q)
+}
@@ -0,0 +1,263 @@
+package queue
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+// test variables
+const pkqsize int = 4
+
+// test Peek on a plain Queue backend
+func peek1(t *testing.T, q Queue[int]) {
+	_, err := q.Peek()
+	if !errors.Is(err, ErrEmpty) {
+		t.Error("err should be ErrEmpty", err)
+	}
+
+	q.Push(1)
+	q.Push(2)
+
+	v, err := q.Peek()
+	if err != nil || v != 1 {
+		t.Error("Peek should return 1", v, err)
+	}
+	if q.Len() != 2 {
+		t.Error("length should == 2", q.Len())
+	}
+
+	// the head doesn't move
+	v, _ = q.Pop()
+	if v != 1 {
+		t.Error("Pop should return 1", v)
+	}
+}
+
+// test the synchronized Peek, TryPeek and PeekN
+func peek2(t *testing.T, q SynchronizedQueue[int]) {
+	_, err := q.TryPeek()
+	if !errors.Is(err, ErrEmpty) {
+		t.Error("err should be ErrEmpty", err)
+	}
+	if len(q.PeekN(2)) != 0 {
+		t.Error("PeekN should be empty")
+	}
+
+	q.Put(1)
+	q.Put(2)
+	q.Put(3)
+
+	v, err := q.TryPeek()
+	if err != nil || v != 1 {
+		t.Error("TryPeek should return 1", v, err)
+	}
+	v, err = q.Peek()
+	if err != nil || v != 1 {
+		t.Error("Peek should return 1", v, err)
+	}
+
+	values := q.PeekN(2)
+	if len(values) != 2 || values[0] != 1 || values[1] != 2 {
+		t.Error("PeekN(2) should return [1 2]", values)
+	}
+	values = q.PeekN(10)
+	if len(values) != 3 || values[2] != 3 {
+		t.Error("PeekN(10) should return [1 2 3]", values)
+	}
+
+	// nothing was removed, and the order is still FIFO
+	if q.Len() != 3 {
+		t.Error("length should == 3", q.Len())
+	}
+	q.Put(4)
+	for i := 1; i <= 4; i++ {
+		v, err = q.Get()
+		if err != nil || v != i {
+			t.Error("v should == i", v, i, err)
+		}
+	}
+}
+
+// a blocked Peek doesn't swallow the wakeup of a blocked Get
+func peek3(t *testing.T, q SynchronizedQueue[int]) {
+	var wg sync.WaitGroup
+	var peeked sync.WaitGroup
+
+	// the Peek blocks first so it is first in line for a wakeup
+	peeked.Add(1)
+	go func() {
+		defer peeked.Done()
+		// depending on who runs first it sees 7 or 8
+		v, err := q.Peek()
+		if err != nil || (v != 7 && v != 8) {
+			t.Error("Peek should return 7 or 8", v, err)
+		}
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		v, err := q.Get()
+		if err != nil || v != 7 {
+			t.Error("Get should return 7", v, err)
+		}
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	// a single Put has to reach the Get
+	q.Put(7)
+	wg.Wait()
+
+	// release the Peek if the Get got there first
+	q.Put(8)
+	peeked.Wait()
+	q.Get()
+
+	// a blocked Peek is woken by Close
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, err := q.Peek()
+		if !errors.Is(err, ErrClosed) {
+			t.Error("err should be ErrClosed", err)
+		}
+	}()
+	time.Sleep(10 * time.Millisecond)
+	q.Close()
+	wg.Wait()
+}
+
+func TestQueuePeek(t *testing.T) {
+	peek1(t, NewCircularQueueOf[int](pkqsize))
+	peek1(t, NewListQueueOf[int](pkqsize))
+	peek1(t, NewRingQueueOf[int](pkqsize))
+	peek1(t, NewSliceQueueOf[int](pkqsize))
+	peek1(t, NewGrowableQueueOf[int](pkqsize))
+}
+
+func TestPriorityPeek(t *testing.T) {
+	q := NewSyncPriorityOf[string](pkqsize)
+
+	q.Put(NewPriorityItem("c", 3))
+	q.Put(NewPriorityItem("a", 1))
+	q.Put(NewPriorityItem("b", 2))
+
+	item, err := q.TryPeek()
+	if err != nil || item.value != "a" {
+		t.Error("TryPeek should return the lowest priority", item, err)
+	}
+
+	items := q.PeekN(3)
+	for i, want := range []string{"a", "b", "c"} {
+		if items[i].value != want {
+			t.Error("PeekN should be in priority order", items)
+		}
+	}
+	if q.Len() != 3 {
+		t.Error("length should == 3", q.Len())
+	}
+}
+
+func peekAll(t *testing.T, factory func() SynchronizedQueue[int]) {
+	peek2(t, factory())
+	peek3(t, factory())
+}
+
+func TestChannelPeek(t *testing.T) {
+	peekAll(t, func() SynchronizedQueue[int] { return NewChannelQueueOf[int](pkqsize) })
+}
+
+func TestCircularPeek(t *testing.T) {
+	peekAll(t, func() SynchronizedQueue[int] { return NewSyncCircularOf[int](pkqsize) })
+}
+
+func TestRingPeek(t *testing.T) {
+	peekAll(t, func() SynchronizedQueue[int] { return NewSyncRingOf[int](pkqsize) })
+}
+
+func TestNativePeek(t *testing.T) {
+	peekAll(t, func() SynchronizedQueue[int] { return NewNativeQueueOf[int](pkqsize) })
+}
+
+// noPeekN is a backend that can't list its elements
+type noPeekN struct {
+	Queue[int]
+}
+
+// PeekN lists nothing from a backend it can't list
+// rather than emptying and refilling it
+func TestNoPeekN(t *testing.T) {
+	b := NewCircularQueueOf[int](pkqsize)
+	q := NewSynchronizedQueueOf[int](noPeekN{b})
+	q.Put(1)
+	q.Put(2)
+
+	if values := q.PeekN(2); len(values) != 0 {
+		t.Error("PeekN should list nothing", values)
+	}
+	if v, err := q.TryGet(); err != nil || v != 1 || q.Len() != 1 {
+		t.Error("the queue should be left alone", v, q.Len(), err)
+	}
+}
+
+// peek while several goroutines get. one producer puts in order,
+// so whatever PeekN sees has to be a run of consecutive values
+func peek4(t *testing.T, q SynchronizedQueue[int], consumers int) {
+	const count = 20000
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < count; i++ {
+			q.Put(i)
+		}
+		q.Close()
+	}()
+
+	got := make([]int, consumers)
+	for c := 0; c < consumers; c++ {
+		wg.Add(1)
+		go func(c int) {
+			defer wg.Done()
+			for {
+				if _, err := q.Get(); err != nil {
+					return
+				}
+				got[c]++
+			}
+		}(c)
+	}
+
+	for {
+		values := q.PeekN(pkqsize)
+		for i := 1; i < len(values); i++ {
+			if values[i] != values[i-1]+1 {
+				t.Error("PeekN should return consecutive values", values)
+			}
+		}
+		if _, err := q.Peek(); errors.Is(err, ErrClosed) {
+			break
+		}
+		// don't starve the others when there is a single cpu
+		runtime.Gosched()
+	}
+	wg.Wait()
+
+	total := 0
+	for _, n := range got {
+		total += n
+	}
+	if total != count {
+		t.Error("every element should be got once", total, count)
+	}
+}
+
+func TestMPMCPeek(t *testing.T) {
+	peekAll(t, func() SynchronizedQueue[int] { return NewMPMCQueueOf[int](pkqsize) })
+	peek4(t, NewMPMCQueueOf[int](pkqsize), 4)
+}
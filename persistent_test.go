@@ -0,0 +1,197 @@
+package queue
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// test variables
+const psqsize int = 16
+
+func openInts(t *testing.T, dir string, options PersistentOptions[int]) *PersistentQueue[int] {
+	q, err := OpenPersistentQueueOf[int](dir, psqsize, options)
+	if err != nil {
+		t.Fatal("open should not fail", err)
+	}
+	return q
+}
+
+// segments returns the log files in dir
+func segments(t *testing.T, dir string) []string {
+	files, err := filepath.Glob(filepath.Join(dir, "*.wal"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+// what is pushed and not popped is there after a reopen
+func persist1(t *testing.T, options PersistentOptions[int]) {
+	dir := t.TempDir()
+
+	q := openInts(t, dir, options)
+	for i := 0; i < 10; i++ {
+		if err := q.Push(i); err != nil {
+			t.Error("Push should not fail", err)
+		}
+	}
+	for i := 0; i < 4; i++ {
+		q.Pop()
+	}
+	q.Close()
+
+	if err := q.Push(99); !errors.Is(err, ErrClosed) {
+		t.Error("err should be ErrClosed", err)
+	}
+
+	q = openInts(t, dir, options)
+	if q.Len() != 6 {
+		t.Error("length should == 6", q.Len())
+	}
+	q.Push(10)
+	for i := 4; i <= 10; i++ {
+		v, err := q.Pop()
+		if err != nil || v != i {
+			t.Error("v should == i", v, i, err)
+		}
+	}
+	if _, err := q.Pop(); !errors.Is(err, ErrEmpty) {
+		t.Error("err should be ErrEmpty", err)
+	}
+	q.Close()
+
+	// and empty after another
+	q = openInts(t, dir, options)
+	if q.Len() != 0 {
+		t.Error("length should == 0", q.Len())
+	}
+	q.Close()
+}
+
+// segments are deleted once their elements have been popped
+func persist2(t *testing.T) {
+	dir := t.TempDir()
+	options := PersistentOptions[int]{SegmentSize: 64, Fsync: FsyncNever}
+
+	// one element more is left behind each round, 30 to 39 in the end
+	q := openInts(t, dir, options)
+	next := 0
+	for round := 0; round < 10; round++ {
+		for i := 0; i < 4; i++ {
+			q.Push(next)
+			next++
+		}
+		for i := 0; i < 3; i++ {
+			q.Pop()
+		}
+	}
+	if n := len(segments(t, dir)); n > 6 {
+		t.Error("popped segments should be deleted", n)
+	}
+	q.Close()
+
+	q = openInts(t, dir, options)
+	if q.Len() != 10 {
+		t.Error("length should == 10", q.Len())
+	}
+	for i := 30; i < 40; i++ {
+		v, err := q.Pop()
+		if err != nil || v != i {
+			t.Error("v should == i", v, i, err)
+		}
+	}
+	if n := len(segments(t, dir)); n != 1 {
+		t.Error("only the last segment should be left", n)
+	}
+	q.Close()
+}
+
+// a record cut short by a crash is dropped, the ones before it kept
+func persist3(t *testing.T) {
+	dir := t.TempDir()
+
+	q := openInts(t, dir, PersistentOptions[int]{})
+	for i := 0; i < 3; i++ {
+		q.Push(i)
+	}
+	q.Close()
+
+	// tear the last record
+	files := segments(t, dir)
+	last := files[len(files)-1]
+	info, _ := os.Stat(last)
+	os.Truncate(last, info.Size()-3)
+
+	q = openInts(t, dir, PersistentOptions[int]{})
+	if q.Len() != 2 {
+		t.Error("length should == 2", q.Len())
+	}
+	// and the log carries on after the good part
+	q.Push(5)
+	q.Close()
+
+	q = openInts(t, dir, PersistentOptions[int]{})
+	for _, want := range []int{0, 1, 5} {
+		v, err := q.Pop()
+		if err != nil || v != want {
+			t.Error("v should == want", v, want, err)
+		}
+	}
+	q.Close()
+
+	// damage before the last segment is an error
+	dir = t.TempDir()
+	q = openInts(t, dir, PersistentOptions[int]{SegmentSize: 1})
+	for i := 0; i < 3; i++ {
+		q.Push(i)
+	}
+	q.Close()
+
+	first := segments(t, dir)[0]
+	data, _ := os.ReadFile(first)
+	data[walHeader+2] ^= 0xff
+	os.WriteFile(first, data, 0o644)
+	if _, err := OpenPersistentQueueOf[int](dir, psqsize, PersistentOptions[int]{}); !errors.Is(err, ErrCorrupt) {
+		t.Error("err should be ErrCorrupt", err)
+	}
+}
+
+// PERSISTENT QUEUE
+func TestPersistent(t *testing.T) {
+	persist1(t, PersistentOptions[int]{})
+	persist1(t, PersistentOptions[int]{Codec: JSONCodec[int]{}, Fsync: FsyncInterval})
+	persist1(t, PersistentOptions[int]{Fsync: FsyncNever, SegmentSize: 32})
+	persist2(t)
+	persist3(t)
+}
+
+// wrapped in a SynchronizedQueue it behaves like any other
+func TestPersistentSync(t *testing.T) {
+	options := PersistentOptions[int]{Fsync: FsyncNever}
+	open := func(cap int) *PersistentQueue[int] {
+		q, err := OpenPersistentQueueOf[int](t.TempDir(), cap, options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { q.Close() })
+		return q
+	}
+
+	iq, err := OpenPersistentQueue(t.TempDir(), sqsize, PersistentOptions[interface{}]{Fsync: FsyncNever})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iq.Close()
+
+	queue1(t, open(sqsize))
+	sync1(t, NewSynchronizedQueue(iq))
+	sync3(t, NewSynchronizedQueueOf[int](open(sqsize)))
+	closeAll(t, func() SynchronizedQueue[int] { return NewSynchronizedQueueOf[int](open(clqsize)) })
+	batchAll(t, func() SynchronizedQueue[int] { return NewSynchronizedQueueOf[int](open(bqcap)) })
+	peekAll(t, func() SynchronizedQueue[int] { return NewSynchronizedQueueOf[int](open(pkqsize)) })
+	context1(t, NewSynchronizedQueueOf[int](open(cqsize)))
+	context2(t, NewSynchronizedQueueOf[int](open(cqsize)))
+	timeout1(t, NewSynchronizedQueueOf[int](open(cqsize)))
+}
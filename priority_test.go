@@ -1,47 +1,49 @@
 package queue
 
 import (
+	"errors"
 	"math/rand"
 	"sort"
+	"sync"
 	"testing"
+	"time"
 )
 
 // test variables
 const hqsize int = 8
 
-
 // test an instance of a SynchronizedQueue
-func heap1(t *testing.T, q SynchronizedQueue) {
+func heap1(t *testing.T, q SynchronizedQueue[interface{}]) {
 	if q == nil {
 		t.Error("q should not be nil")
 	}
 
 	// check length
 	if q.Len() != 0 {
-		t.Error("length should be 0",q.Len())
+		t.Error("length should be 0", q.Len())
 	}
 
 	// check capacity
 	if q.Cap() != hqsize {
-		t.Error("capacity should == hqsize",q.Cap(),hqsize)
+		t.Error("capacity should == hqsize", q.Cap(), hqsize)
 	}
 
 	// create a list of random positive values
-	rval := make(PrioritySlice,q.Cap())
-	for i:=0;i<len(rval);i++ {
+	rval := make(PrioritySlice[interface{}], q.Cap())
+	for i := 0; i < len(rval); i++ {
 		j := rand.Intn(100)
-		rval[i].value = j*10
+		rval[i].value = j * 10
 		rval[i].priority = j
 	}
-	
+
 	// insert it the priority queue
-	for i:=0;i<q.Cap();i++ {
+	for i := 0; i < q.Cap(); i++ {
 		q.TryPut(rval[i])
 		//length should be == i at this point
-		if q.Len() != (i+1) {
-			t.Error("length should == i+1",q.Len(),i+1)
+		if q.Len() != (i + 1) {
+			t.Error("length should == i+1", q.Len(), i+1)
 		}
-		// print it 
+		// print it
 		// fmt.Println(rval[i])
 	}
 
@@ -50,10 +52,9 @@ func heap1(t *testing.T, q SynchronizedQueue) {
 		t.Error("length should == capacity")
 	}
 
-	// cleanup 
-	// for channels, this closes it for further Puts
+	// cleanup
+	// this closes it for further Puts
 	// any remaing data is still available for Gets
-	// it is a noop for the mutex/condition variable methods
 	q.Close()
 
 	// fmt.Println()
@@ -64,32 +65,382 @@ func heap1(t *testing.T, q SynchronizedQueue) {
 	// remove all items. no need to block
 	// should come out in ascending order
 	j := q.Len() - 1
-	for i:=0;i<q.Cap();i++ {
+	for i := 0; i < q.Cap(); i++ {
 		value, err := q.TryGet()
 		if err != nil {
 			t.Error(err)
 		}
 		// assert convert to int
-		v := value.(PriorityItem)
+		v := value.(PriorityItem[interface{}])
 		if v.priority != rval[i].priority {
 			t.Error("heap not in order")
 		}
 		// fmt.Println(v)
 		// length should decrease
 		if q.Len() != j {
-			t.Error("length should == i",q.Len(),j)
+			t.Error("length should == i", q.Len(), j)
 		}
 		j--
 	}
 
 	// check the length == 0
 	if q.Len() != 0 {
-		t.Error("length should == 0",q.Len())
+		t.Error("length should == 0", q.Len())
 	}
 }
 
 // PRIORITY QUEUE using SynchronizedQueue wrapper
 func TestPrioritySync(t *testing.T) {
-	// test for heap to check priority  
-	heap1(t,NewSyncPriority(hqsize))
+	// test for heap to check priority
+	heap1(t, NewSyncPriority(hqsize))
+}
+
+// PRIORITY QUEUE with a typed value
+func TestPriorityTypedSync(t *testing.T) {
+	q := NewSyncPriorityOf[string](hqsize)
+
+	q.Put(NewPriorityItem("c", 3))
+	q.Put(NewPriorityItem("a", 1))
+	q.Put(NewPriorityItem("b", 2))
+
+	for _, want := range []string{"a", "b", "c"} {
+		item, err := q.TryGet()
+		if err != nil {
+			t.Error(err)
+		}
+		if item.value != want {
+			t.Error("item.value should == want", item.value, want)
+		}
+	}
+}
+
+// PRIORITY QUEUE through the value and priority API
+func TestPriorityPutGet(t *testing.T) {
+	q := NewSyncPriorityOf[string](hqsize)
+
+	q.PutPriority("c", 3)
+	q.TryPutPriority("a", 1)
+	q.PutPriority("b", 2)
+
+	for i, want := range []string{"a", "b", "c"} {
+		var v string
+		var p int
+		var err error
+		if i == 0 {
+			v, p, err = q.TryGetPriority()
+		} else {
+			v, p, err = q.GetPriority()
+		}
+		if err != nil || v != want || p != i+1 {
+			t.Error("should get want with priority i+1", v, p, want, i+1, err)
+		}
+	}
+
+	_, _, err := q.TryGetPriority()
+	if !errors.Is(err, ErrEmpty) {
+		t.Error("err should be ErrEmpty", err)
+	}
+
+	// the item accessors
+	item := NewPriorityItem("x", 7)
+	if item.Value() != "x" || item.Priority() != 7 {
+		t.Error("item should hold x with priority 7", item.Value(), item.Priority())
+	}
+}
+
+// PRIORITY QUEUE of interface{} rejects values that are not items
+func TestPriorityType(t *testing.T) {
+	q := NewSyncPriority(hqsize)
+
+	err := q.Put(42)
+	if !errors.Is(err, ErrType) {
+		t.Error("err should be ErrType", err)
+	}
+
+	err = q.Put(NewPriorityItem[interface{}](42, 1))
+	if err != nil {
+		t.Error(err)
+	}
+	value, err := q.Get()
+	if err != nil || value.(PriorityItem[interface{}]).Value() != 42 {
+		t.Error("Get should return 42", value, err)
+	}
+}
+
+// equal priorities come out first come first served
+func stable1(t *testing.T, q *SyncPriorityQueue[int], order PriorityOrder) {
+	// values 0..n-1 with only a few distinct priorities
+	n := q.Cap()
+	for i := 0; i < n; i++ {
+		if _, err := q.TryPutPriority(i, rand.Intn(3)); err != nil {
+			t.Error(err)
+		}
+	}
+
+	last := map[int]int{}
+	prevp := q.PeekN(1)[0].Priority()
+	for i := 0; i < n; i++ {
+		v, p, err := q.TryGetPriority()
+		if err != nil {
+			t.Error(err)
+		}
+
+		// priorities are in order
+		if (order == MinFirst && p < prevp) || (order == MaxFirst && p > prevp) {
+			t.Error("priority out of order", p, prevp)
+		}
+
+		// and values are in insertion order within a priority
+		if l, ok := last[p]; ok && v < l {
+			t.Error("equal priorities out of order", p, v, l)
+		}
+		last[p] = v
+		prevp = p
+	}
+}
+
+// PRIORITY QUEUE is stable for equal priorities
+func TestPriorityStable(t *testing.T) {
+	stable1(t, NewSyncPriorityOrderOf[int](1000, MinFirst), MinFirst)
+	stable1(t, NewSyncPriorityOrderOf[int](1000, MaxFirst), MaxFirst)
+
+	// interleaved puts and gets keep the order too
+	q := NewSyncPriorityOf[int](hqsize)
+	next := 0
+	for i := 0; i < 100; i++ {
+		for q.Len() < q.Cap() {
+			q.PutPriority(next, 0)
+			next++
+		}
+		v, _, err := q.GetPriority()
+		if err != nil || v != i {
+			t.Error("v should == i", v, i, err)
+		}
+	}
+}
+
+// PRIORITY QUEUE ordered by a less function
+func TestPriorityFunc(t *testing.T) {
+	type job struct {
+		name     string
+		deadline time.Time
+	}
+
+	// earliest deadline first
+	now := time.Now()
+	q := NewSyncPriorityFunc(hqsize, func(a, b job) bool {
+		return a.deadline.Before(b.deadline)
+	})
+	q.Put(job{"c", now.Add(3 * time.Second)})
+	q.Put(job{"a", now.Add(1 * time.Second)})
+	q.Put(job{"b", now.Add(2 * time.Second)})
+
+	head, err := q.TryPeek()
+	if err != nil || head.name != "a" {
+		t.Error("TryPeek should return the earliest deadline", head, err)
+	}
+	for _, want := range []string{"a", "b", "c"} {
+		v, err := q.Get()
+		if err != nil || v.name != want {
+			t.Error("should get want", v.name, want, err)
+		}
+	}
+
+	// descending, equal elements first come first served
+	type key struct{ major, minor int }
+	kq := NewPriorityQueueFunc(hqsize, func(a, b key) bool { return a.major > b.major })
+	kq.Push(key{1, 0})
+	kq.Push(key{2, 0})
+	kq.Push(key{1, 1})
+	kq.Push(key{2, 1})
+	for _, want := range []key{{2, 0}, {2, 1}, {1, 0}, {1, 1}} {
+		v, err := kq.Pop()
+		if err != nil || v != want {
+			t.Error("should pop want", v, want, err)
+		}
+	}
+
+	// a plain Queue backend
+	queue1(t, NewPriorityQueueFunc(sqsize, func(a, b int) bool { return a < b }))
+	peek1(t, NewPriorityQueueFunc(pkqsize, func(a, b int) bool { return a < b }))
+}
+
+// PRIORITY QUEUE items are reprioritized and removed by handle
+func TestPriorityHandle(t *testing.T) {
+	q := NewSyncPriorityOf[string](hqsize)
+
+	ha, _ := q.PutPriority("a", 1)
+	hb, _ := q.PutPriority("b", 2)
+	hc, _ := q.TryPutPriority("c", 3)
+	q.PutPriority("d", 4)
+
+	// c jumps to the front, a goes to the back
+	if err := q.UpdatePriority(hc, 0); err != nil {
+		t.Error(err)
+	}
+	if err := q.UpdatePriority(ha, 9); err != nil {
+		t.Error(err)
+	}
+
+	// b is cancelled
+	v, err := q.Remove(hb)
+	if err != nil || v != "b" {
+		t.Error("Remove should return b", v, err)
+	}
+	if _, err = q.Remove(hb); !errors.Is(err, ErrNotQueued) {
+		t.Error("err should be ErrNotQueued", err)
+	}
+
+	for _, want := range []string{"c", "d", "a"} {
+		v, _, err := q.GetPriority()
+		if err != nil || v != want {
+			t.Error("should get want", v, want, err)
+		}
+	}
+
+	// a handle is dead once its item is got
+	if err = q.UpdatePriority(ha, 1); !errors.Is(err, ErrNotQueued) {
+		t.Error("err should be ErrNotQueued", err)
+	}
+	if err = q.UpdatePriority(nil, 1); !errors.Is(err, ErrNotQueued) {
+		t.Error("err should be ErrNotQueued", err)
+	}
+}
+
+// PRIORITY QUEUE Remove wakes a blocked Put
+func TestPriorityRemoveWakes(t *testing.T) {
+	var wg sync.WaitGroup
+
+	q := NewSyncPriorityOf[int](hqsize)
+	handles := make([]*PriorityHandle, q.Cap())
+	for i := range handles {
+		handles[i], _ = q.PutPriority(i, i)
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if _, err := q.PutPriority(99, 99); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	q.Remove(handles[3])
+	wg.Wait()
+
+	if q.Len() != q.Cap() {
+		t.Error("length should == capacity", q.Len())
+	}
+}
+
+// PRIORITY QUEUE handles stay valid through heap churn
+func TestPriorityHandleChurn(t *testing.T) {
+	q := NewPriorityQueueOf[int](1000).(*PriorityQueue[int])
+
+	handles := map[int]*PriorityHandle{}
+	for i := 0; i < 1000; i++ {
+		handles[i], _ = q.PushPriority(i, rand.Intn(100))
+	}
+
+	// reprioritize and remove at random, then check every handle
+	for i := 0; i < 1000; i += 2 {
+		q.UpdatePriority(handles[i], rand.Intn(100))
+	}
+	for i := 1; i < 1000; i += 4 {
+		item, err := q.Remove(handles[i])
+		if err != nil || item.Value() != i {
+			t.Error("Remove should return i", item.Value(), i, err)
+		}
+		delete(handles, i)
+	}
+	for i, h := range handles {
+		if q.heap.PrioritySlice[h.index].Value() != i {
+			t.Error("handle should point at its item", i)
+		}
+	}
+
+	// and the heap still pops in order
+	prev := -1
+	for q.Len() > 0 {
+		item, _ := q.Pop()
+		if item.Priority() < prev {
+			t.Error("heap not in order")
+		}
+		prev = item.Priority()
+	}
+}
+
+// fakeClock is a deterministic clock for aging
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+// a low priority item under a stream of urgent ones is got
+// once it has waited long enough
+func aging1(t *testing.T, order PriorityOrder, age AgingFunc, low, high int, want int) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	q := NewSyncPriorityAgingOf[string](hqsize, order, PriorityAging{Age: age, Now: clock.Now})
+
+	q.PutPriority("starving", low)
+	for i := 1; i <= 100; i++ {
+		clock.now = clock.now.Add(time.Second)
+		q.PutPriority("urgent", high)
+
+		v, p, err := q.GetPriority()
+		if err != nil {
+			t.Error(err)
+		}
+		if v == "starving" {
+			// the original priority is returned
+			if p != low {
+				t.Error("priority should == low", p, low)
+			}
+			if i != want {
+				t.Error("should be got after want ticks", i, want)
+			}
+			return
+		}
+	}
+	t.Error("the low priority item starved")
+}
+
+// PRIORITY QUEUE with aging doesn't starve items
+func TestPriorityAging(t *testing.T) {
+	// one level a second, it catches up with the urgent ones after
+	// 9 seconds and wins the tie because it was put first
+	aging1(t, MinFirst, AgeEvery(time.Second), 10, 1, 9)
+	aging1(t, MaxFirst, AgeEvery(time.Second), 1, 10, 9)
+
+	// a step function, a big boost after 5 seconds
+	step := func(waited time.Duration) int {
+		if waited >= 5*time.Second {
+			return 100
+		}
+		return 0
+	}
+	aging1(t, MinFirst, step, 50, 1, 5)
+
+	// a step that isn't positive turns aging off
+	for _, d := range []time.Duration{0, -time.Second} {
+		if boost := AgeEvery(d)(time.Hour); boost != 0 {
+			t.Error("AgeEvery should not age", d, boost)
+		}
+	}
+
+	// without the clock moving it is an ordinary stable priority queue
+	q := NewPriorityQueueAgingOf[int](hqsize, MinFirst, PriorityAging{Age: AgeEvery(time.Hour)})
+	q.Push(NewPriorityItem(1, 2))
+	q.Push(NewPriorityItem(2, 1))
+	q.Push(NewPriorityItem(3, 2))
+	for _, want := range []int{2, 1, 3} {
+		item, err := q.Pop()
+		if err != nil || item.Value() != want {
+			t.Error("should pop want", item.Value(), want, err)
+		}
+	}
 }
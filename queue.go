@@ -1,9 +1,37 @@
 package queue
 
-import "fmt"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrFull is returned when an element is added to a full queue
+var ErrFull = errors.New("queue is full")
+
+// ErrEmpty is returned when an element is taken from an empty queue
+var ErrEmpty = errors.New("queue is empty")
+
+// ErrClosed is returned by Put after the queue is closed
+// and by Get once a closed queue has been drained
+var ErrClosed = errors.New("queue is closed")
+
+// ErrTimeout is returned by PutTimeout and GetTimeout
+// when the operation could not complete in time
+var ErrTimeout = errors.New("queue operation timed out")
+
+// ErrType is returned when an element of the wrong type is added
+// to a queue created by one of the interface{} factories
+var ErrType = errors.New("queue element has the wrong type")
+
+// ErrNotQueued is returned when a handle refers to an item
+// that has already left the queue
+var ErrNotQueued = errors.New("item is not in the queue")
 
 // Queue - interface for a simple, non-thread-safe queue
-type Queue interface {
+// holding elements of type T
+type Queue[T any] interface {
 	// current number of elements in the queue
 	Len() int
 
@@ -11,45 +39,218 @@ type Queue interface {
 	Cap() int
 
 	// enqueue a value on the tail of the queue
-	Push(value interface{})  error
+	Push(value T) error
 
 	// dequeue and return a value from the head of the queue
-	Pop() (interface{},error)
+	Pop() (T, error)
+
+	// return the value at the head of the queue without removing it
+	Peek() (T, error)
 
 	// string representation
 	fmt.Stringer
 }
 
 // SynchronizedQueue is a queue with a bound on the number of elements in the queue
-// this interface does not promise thread-safety
-type  SynchronizedQueue interface {
+// any implementation of this SHOULD promise thread-safety and the proper blocking semantics
+type SynchronizedQueue[T any] interface {
 
 	// add an element onto the tail queue
 	// if the queue is full, the caller blocks
- 	Put(value interface{}) 
+	// if the queue is closed ErrClosed is returned
+	Put(value T) error
 
 	// add an element onto the tail queue
 	// if the queue is full an error is returned
-	TryPut(value interface{}) error
+	// if the queue is closed ErrClosed is returned
+	TryPut(value T) error
+
+	// add an element onto the tail queue
+	// if the queue is full, the caller blocks until there is room
+	// or the context is done, in which case ctx.Err() is returned
+	PutContext(ctx context.Context, value T) error
+
+	// add an element onto the tail queue
+	// if the queue is full, the caller blocks for at most d
+	// and ErrTimeout is returned if there is still no room
+	PutTimeout(value T, d time.Duration) error
 
 	// get an element from the head of the queue
 	// if the queue is empty the caller blocks
-	Get() interface{}
+	// if the queue is closed and empty ErrClosed is returned
+	Get() (T, error)
 
 	// try to get an element from the head of the queue
 	// if the queue is empty an error is returned
-	TryGet() (interface{}, error)
+	// if the queue is closed and empty ErrClosed is returned
+	TryGet() (T, error)
 
-	// current number of elements in the queue 
- 	Len() int
+	// get an element from the head of the queue
+	// if the queue is empty, the caller blocks until there is an element
+	// or the context is done, in which case ctx.Err() is returned
+	GetContext(ctx context.Context) (T, error)
+
+	// get an element from the head of the queue
+	// if the queue is empty, the caller blocks for at most d
+	// and ErrTimeout is returned if there is still no element
+	GetTimeout(d time.Duration) (T, error)
+
+	// add as many of values as there is room for onto the tail queue
+	// without blocking. returns the number added, with ErrFull
+	// if not all of them fit
+	PutMany(values []T) (int, error)
+
+	// add values onto the tail queue, blocking until at least one
+	// of them can be added or the context is done.
+	// returns the number added
+	PutManyContext(ctx context.Context, values []T) (int, error)
+
+	// get up to max elements from the head of the queue into dst
+	// without blocking. returns the number copied, with ErrEmpty
+	// if there were none
+	GetMany(dst []T, max int) (int, error)
+
+	// get up to max elements from the head of the queue into dst,
+	// blocking until at least min of them are available or the
+	// context is done. returns the number copied
+	GetManyContext(ctx context.Context, dst []T, min, max int) (int, error)
+
+	// return the element at the head of the queue without removing it
+	// if the queue is empty the caller blocks
+	// if the queue is closed and empty ErrClosed is returned
+	Peek() (T, error)
+
+	// return the element at the head of the queue without removing it
+	// if the queue is empty an error is returned
+	// if the queue is closed and empty ErrClosed is returned
+	TryPeek() (T, error)
+
+	// return up to n elements from the head of the queue without
+	// removing them, in the order Get would return them
+	PeekN(n int) []T
+
+	// current number of elements in the queue
+	Len() int
 
 	// capacity maximum number of elements the queue can hold
 	Cap() int
 
-	// close any resources (required for channel version)
+	// stop accepting elements and wake up all blocked callers
+	// remaining elements can still be drained. calling it again is a noop
 	Close()
-	
+
 	// string representation
 	fmt.Stringer
 }
 
+// batchQueue is implemented by Queue backends that can move
+// several elements at once, e.g. with copy() into a circular buffer.
+// SynchronizedQueueImpl uses it when available
+type batchQueue[T any] interface {
+	// add as many of values as fit, returning the number added
+	PushMany(values []T) int
+
+	// remove up to len(dst) elements into dst, returning the number removed
+	PopMany(dst []T) int
+}
+
+// peekNQueue is implemented by Queue backends that can list
+// the elements at their head without removing them.
+// SynchronizedQueueImpl uses it when available
+type peekNQueue[T any] interface {
+	// return up to n elements in the order Pop would return them
+	PeekN(n int) []T
+}
+
+// peekN lists up to n elements at the head of q without removing them.
+// every backend in this package can do it. for one that can't,
+// nothing is listed rather than emptying and refilling it
+func peekN[T any](q Queue[T], n int) []T {
+	if pq, ok := q.(peekNQueue[T]); ok {
+		return pq.PeekN(n)
+	}
+	return []T{}
+}
+
+// batchLimits clamps the requested batch bounds to the size of dst.
+// negative bounds count as 0, and at least one element is waited for
+// when anything can be returned
+func batchLimits(dst int, min, max int) (int, int) {
+	if max > dst {
+		max = dst
+	}
+	if max < 0 {
+		max = 0
+	}
+	if min < 0 {
+		min = 0
+	}
+	if min > max {
+		min = max
+	}
+	if min < 1 && max > 0 {
+		min = 1
+	}
+	return min, max
+}
+
+// timeoutError maps the expiry of a timeout context to ErrTimeout
+func timeoutError(err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrTimeout
+	}
+	return err
+}
+
+// anyQueue adapts a typed Queue[T] to the interface{} API.
+// it is the compatibility layer for backends whose element type
+// is not interface{} (e.g. the PriorityQueue of PriorityItem)
+type anyQueue[T any] struct {
+	queue Queue[T] // the typed queue being adapted
+}
+
+func (aq *anyQueue[T]) Len() int {
+	return aq.queue.Len()
+}
+
+func (aq *anyQueue[T]) Cap() int {
+	return aq.queue.Cap()
+}
+
+func (aq *anyQueue[T]) Push(value interface{}) error {
+	v, ok := value.(T)
+	if !ok {
+		return fmt.Errorf("%w: %T", ErrType, value)
+	}
+	return aq.queue.Push(v)
+}
+
+func (aq *anyQueue[T]) Pop() (interface{}, error) {
+	value, err := aq.queue.Pop()
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+func (aq *anyQueue[T]) Peek() (interface{}, error) {
+	value, err := aq.queue.Peek()
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+func (aq *anyQueue[T]) PeekN(n int) []interface{} {
+	values := peekN(aq.queue, n)
+	s := make([]interface{}, len(values))
+	for i, value := range values {
+		s[i] = value
+	}
+	return s
+}
+
+// String
+func (aq *anyQueue[T]) String() string {
+	return aq.queue.String()
+}
@@ -1,95 +1,447 @@
 package queue
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"runtime"
+	"sync"
+	"time"
 )
 
 // ChannelQ is a type of queue that uses a
-// condition variable and lists to implement the
+// buffered channel to implement the
 // SynchronizedQueue interface. this implementation
 // is intended to be thread safe
-type ChannelQ struct {
-	channel chan interface{} // buffered channel with specified capacity 
+// the data channel itself is never closed. Close closes
+// a separate done channel so late Puts fail instead of panicking
+//
+// a channel can't show its head without a receive, so Peek receives
+// the element into the peeked slice where Get finds it first.
+// the peeked slice is owned by whoever holds the getter token, and
+// the slots channel holds one token per element so elements parked
+// in peeked still count against the capacity
+type ChannelQ[T any] struct {
+	channel chan T        // buffered channel with specified capacity
+	slots   chan struct{} // one token per element in the queue
+	getter  chan struct{} // token held by the consumer using channel and peeked
+	peeked  []T           // elements received by Peek but not yet by Get
+	done    chan struct{} // closed by Close to wake up blocked callers
+	once    sync.Once     // makes Close idempotent
+}
+
+// isClosed reports whether Close has been called
+func (chq *ChannelQ[T]) isClosed() bool {
+	select {
+	case <-chq.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// emptyError is the error for an empty queue
+func (chq *ChannelQ[T]) emptyError() error {
+	if chq.isClosed() {
+		return ErrClosed
+	}
+	return ErrEmpty
+}
+
+// lock takes the getter token, blocking until it is free
+// or the context is done
+func (chq *ChannelQ[T]) lock(ctx context.Context) error {
+	select {
+	case chq.getter <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// tryLock takes the getter token without waiting on a consumer
+// that is blocked on an empty queue. it gives up if the queue is empty
+func (chq *ChannelQ[T]) tryLock() bool {
+	for {
+		select {
+		case chq.getter <- struct{}{}:
+			return true
+		default:
+		}
+		// the holder is about to take or park the elements
+		if chq.Len() == 0 {
+			return false
+		}
+		runtime.Gosched()
+	}
+}
+
+// unlock releases the getter token
+func (chq *ChannelQ[T]) unlock() {
+	<-chq.getter
+}
+
+// take removes the oldest peeked element
+// the caller holds the getter token
+func (chq *ChannelQ[T]) take() T {
+	var zero T
+
+	value := chq.peeked[0]
+	chq.peeked[0] = zero
+	chq.peeked = chq.peeked[1:]
+
+	// free its slot
+	<-chq.slots
+
+	return value
+}
+
+// receive returns the element at the head of the queue, blocking
+// until there is one or the context is done. with park set the element
+// is kept in peeked, otherwise it is removed from the queue.
+// the caller holds the getter token
+func (chq *ChannelQ[T]) receive(ctx context.Context, park bool) (T, error) {
+	var zero T
+	var value T
+
+	if len(chq.peeked) > 0 {
+		if park {
+			return chq.peeked[0], nil
+		}
+		return chq.take(), nil
+	}
+
+	select {
+	case value = <-chq.channel:
+	case <-chq.done:
+		// drain whatever is left
+		return chq.tryReceive(park)
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	}
+
+	if park {
+		chq.peeked = append(chq.peeked, value)
+	} else {
+		<-chq.slots
+	}
+	return value, nil
+}
+
+// tryReceive is receive without blocking
+// the caller holds the getter token
+func (chq *ChannelQ[T]) tryReceive(park bool) (T, error) {
+	var zero T
+	var value T
+
+	if len(chq.peeked) > 0 {
+		if park {
+			return chq.peeked[0], nil
+		}
+		return chq.take(), nil
+	}
+
+	select {
+	case value = <-chq.channel:
+	default:
+		return zero, chq.emptyError()
+	}
+
+	if park {
+		chq.peeked = append(chq.peeked, value)
+	} else {
+		<-chq.slots
+	}
+	return value, nil
 }
 
 // TryPut adds an element onto the tail queue
 // if the queue is full, an error is returned
-func (chq *ChannelQ) TryPut(value interface{}) error {
-	var err error
-
-	err = nil
+func (chq *ChannelQ[T]) TryPut(value T) error {
+	if chq.isClosed() {
+		return ErrClosed
+	}
 
-	// attempt to insert the value into the buffered channel
+	// attempt to reserve a slot
 	select {
-	// send it if there is room
-	case chq.channel <- value:
+	case chq.slots <- struct{}{}:
 		// no action
 	default:
-		// couldn't send, buffered channel is full
-		err = errors.New("queue is full")
+		// couldn't reserve, queue is full
+		return ErrFull
 	}
 
-	return err
-} 
+	// the channel always has room for a reserved slot
+	chq.channel <- value
+
+	return nil
+}
 
 // Put adds an element to the tail of the queue
 // if the queue is full the function blocks
-func (chq *ChannelQ) Put(value interface{}) {
+func (chq *ChannelQ[T]) Put(value T) error {
+	// a background context is never done
+	return chq.PutContext(context.Background(), value)
+}
+
+// PutContext adds an element to the tail of the queue
+// if the queue is full the function blocks until there is room
+// or the context is done
+func (chq *ChannelQ[T]) PutContext(ctx context.Context, value T) error {
+	if chq.isClosed() {
+		return ErrClosed
+	}
+
+	select {
+	case chq.slots <- struct{}{}:
+		// no action
+	case <-chq.done:
+		return ErrClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	// the channel always has room for a reserved slot
 	chq.channel <- value
+
+	return nil
+}
+
+// PutTimeout adds an element onto the tail queue
+// if the queue is full the function blocks for at most d
+// and returns ErrTimeout if there is still no room
+func (chq *ChannelQ[T]) PutTimeout(value T, d time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+
+	return timeoutError(chq.PutContext(ctx, value))
 }
 
 // Get returns an element from the head of the queue
 // if the queue is empty,the caller blocks
-func (chq *ChannelQ) Get() interface{} {
-	// get a value or block
-	return <-chq.channel
+// once the queue is closed and drained ErrClosed is returned
+func (chq *ChannelQ[T]) Get() (T, error) {
+	// a background context is never done
+	return chq.GetContext(context.Background())
+}
+
+// GetContext returns an element from the head of the queue
+// if the queue is empty the caller blocks until there is an element
+// or the context is done
+func (chq *ChannelQ[T]) GetContext(ctx context.Context) (T, error) {
+	var zero T
+
+	if err := chq.lock(ctx); err != nil {
+		return zero, err
+	}
+	defer chq.unlock()
+
+	return chq.receive(ctx, false)
+}
+
+// GetTimeout returns an element from the head of the queue
+// if the queue is empty the function blocks for at most d
+// and returns ErrTimeout if there is still no element
+func (chq *ChannelQ[T]) GetTimeout(d time.Duration) (T, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+
+	value, err := chq.GetContext(ctx)
+	return value, timeoutError(err)
 }
 
 // TryGet gets a value or returns an error if the queue is empty
-func (chq *ChannelQ) TryGet() (interface{}, error) {
+func (chq *ChannelQ[T]) TryGet() (T, error) {
+	var zero T
+
+	if !chq.tryLock() {
+		return zero, chq.emptyError()
+	}
+	defer chq.unlock()
+
+	return chq.tryReceive(false)
+}
+
+// PutMany adds as many of values as there is room for onto the tail queue
+// a channel has no batch send, so they go in one at a time.
+// ErrFull is returned if not all of them fit
+func (chq *ChannelQ[T]) PutMany(values []T) (int, error) {
+	for i, value := range values {
+		if err := chq.TryPut(value); err != nil {
+			return i, err
+		}
+	}
+	return len(values), nil
+}
+
+// PutManyContext adds values onto the tail queue
+// if the queue is full the function blocks until there is room for
+// at least one of them or the context is done
+func (chq *ChannelQ[T]) PutManyContext(ctx context.Context, values []T) (int, error) {
+	if len(values) == 0 {
+		return 0, nil
+	}
+
+	// block for the first one only
+	if err := chq.PutContext(ctx, values[0]); err != nil {
+		return 0, err
+	}
+
+	n, err := chq.PutMany(values[1:])
+	if errors.Is(err, ErrFull) {
+		err = nil
+	}
+	return n + 1, err
+}
+
+// GetMany copies up to max elements from the head of the queue into dst
+// ErrEmpty is returned if there were none
+func (chq *ChannelQ[T]) GetMany(dst []T, max int) (int, error) {
 	var err error
-	var value interface{}
 
-	value = nil
-	select {
-	case value = <-chq.channel:
-		// no action
-	default:
-		err = errors.New("queue is empty")
+	_, max = batchLimits(len(dst), 0, max)
+	if max == 0 {
+		return 0, nil
+	}
+
+	if !chq.tryLock() {
+		return 0, chq.emptyError()
+	}
+	defer chq.unlock()
+
+	n := 0
+	for n < max {
+		dst[n], err = chq.tryReceive(false)
+		if err != nil {
+			break
+		}
+		n++
+	}
+
+	if n > 0 {
+		return n, nil
+	}
+	return 0, err
+}
+
+// GetManyContext copies up to max elements from the head of the queue
+// into dst. the function blocks until min elements have been received
+// or the context is done, in which case the elements already received
+// are returned along with the error
+func (chq *ChannelQ[T]) GetManyContext(ctx context.Context, dst []T, min, max int) (int, error) {
+	var err error
+
+	min, max = batchLimits(len(dst), min, max)
+
+	n := 0
+	for n < min {
+		dst[n], err = chq.GetContext(ctx)
+		if err != nil {
+			// a closed queue returns whatever was left
+			if errors.Is(err, ErrClosed) && n > 0 {
+				err = nil
+			}
+			return n, err
+		}
+		n++
 	}
-	
-	return value,err
+
+	m, _ := chq.GetMany(dst[n:], max-n)
+	return n + m, nil
 }
 
-// Len is the current number of elements in the queue 
-func (chq *ChannelQ) Len() int {
-	return len(chq.channel)
+// Peek returns the element at the head of the queue without removing it
+// if the queue is empty the caller blocks
+// once the queue is closed and drained ErrClosed is returned
+func (chq *ChannelQ[T]) Peek() (T, error) {
+	// a background context is never done
+	ctx := context.Background()
+
+	chq.lock(ctx)
+	defer chq.unlock()
+
+	return chq.receive(ctx, true)
+}
+
+// TryPeek returns the element at the head of the queue without removing it
+// if the queue is empty returns an error
+func (chq *ChannelQ[T]) TryPeek() (T, error) {
+	var zero T
+
+	if !chq.tryLock() {
+		return zero, chq.emptyError()
+	}
+	defer chq.unlock()
+
+	return chq.tryReceive(true)
+}
+
+// PeekN returns up to n elements from the head of the queue
+// without removing them
+func (chq *ChannelQ[T]) PeekN(n int) []T {
+	if !chq.tryLock() {
+		return []T{}
+	}
+	defer chq.unlock()
+
+	// park more elements until there are enough
+	for len(chq.peeked) < n {
+		select {
+		case value := <-chq.channel:
+			chq.peeked = append(chq.peeked, value)
+			continue
+		default:
+		}
+		break
+	}
+
+	n = max(0, min(n, len(chq.peeked)))
+	values := make([]T, n)
+	copy(values, chq.peeked)
+	return values
+}
+
+// Len is the current number of elements in the queue
+func (chq *ChannelQ[T]) Len() int {
+	return len(chq.slots)
 }
 
 // Cap is the maximum number of elements the queue can hold
-func (chq *ChannelQ) Cap() int {
-	return cap(chq.channel)
+func (chq *ChannelQ[T]) Cap() int {
+	return cap(chq.slots)
 }
 
-// Close required to close the channel so it doesn't leak
-// should only be called by Put threads
-func (chq *ChannelQ) Close() {
-	close(chq.channel)
+// Close stops the queue from accepting more elements
+// and wakes up every blocked Put and Get.
+// elements already in the channel can still be drained by Get
+func (chq *ChannelQ[T]) Close() {
+	chq.once.Do(func() {
+		close(chq.done)
+	})
 }
 
 // String
-func (chq *ChannelQ) String() string {
-	return fmt.Sprintf("ChannelQ Len:%v Cap:%v",chq.Len(),chq.Cap())
+func (chq *ChannelQ[T]) String() string {
+	return fmt.Sprintf("ChannelQ Len:%v Cap:%v", chq.Len(), chq.Cap())
 }
 
-// NewChannelQueue is a factory for creating bounded queues
-// that uses a channel
-// It returns an instance of pointer to SynchronizedQueue
-func NewChannelQueue(size int) SynchronizedQueue {
-	var chq ChannelQ
+// NewChannelQueueOf is a factory for creating bounded queues
+// of elements of type T that use a channel
+// It returns an instance of SynchronizedQueue
+func NewChannelQueueOf[T any](size int) SynchronizedQueue[T] {
+	var chq ChannelQ[T]
 
-	chq.channel = make(chan interface{}, size)
+	chq.channel = make(chan T, size)
+	chq.slots = make(chan struct{}, size)
+	chq.getter = make(chan struct{}, 1)
+	chq.done = make(chan struct{})
 
 	return &chq
 }
+
+// NewChannelQueue is a factory for creating bounded queues
+// that uses a channel
+// It returns an instance of pointer to SynchronizedQueue
+func NewChannelQueue(size int) SynchronizedQueue[interface{}] {
+	return NewChannelQueueOf[interface{}](size)
+}
@@ -1,75 +1,202 @@
 package queue
 
 import (
-	"errors"
 	"fmt"
 )
 
 // Implementation of Queue interface using circular buffer
-type CircularQueue struct {
-	queue []interface{}	// data
-	head     int		// items are pulled from the head
-	tail     int		// items are pushed to the tail
-	length   int		// current number of elements in the queue
-	capacity int	    // maximum allowed elements total
+type CircularQueue[T any] struct {
+	queue    []T            // data
+	head     int            // items are pulled from the head
+	tail     int            // items are pushed to the tail
+	length   int            // current number of elements in the queue
+	capacity int            // maximum allowed elements total
+	policy   OverflowPolicy // what a Push does when the queue is full
+	evict    func(value T)  // called with each element the policy drops
+	dropped  int            // number of elements the policy dropped
 }
 
-func (cb *CircularQueue) Len() int {
+func (cb *CircularQueue[T]) Len() int {
 	return cb.length
 }
 
-func (cb *CircularQueue) Cap() int {
+func (cb *CircularQueue[T]) Cap() int {
 	return cb.capacity
 }
 
-func (cb *CircularQueue) Push(value interface{}) error {
+func (cb *CircularQueue[T]) Push(value T) error {
 	if cb.length >= cb.capacity {
-		return errors.New("queue is full")
+		return cb.overflow(value)
 	}
 	// insert and count
 	cb.queue[cb.tail] = value
-	cb.tail = (cb.tail+1) % cb.capacity
+	cb.tail = (cb.tail + 1) % cb.capacity
 	cb.length++
 
 	return nil
 }
 
-func (cb *CircularQueue) Pop() (interface{}, error) {
+func (cb *CircularQueue[T]) Pop() (T, error) {
+	var zero T
+
 	if cb.length == 0 {
-		return nil, errors.New("queue is empty)")
+		return zero, ErrEmpty
 	}
 	value := cb.queue[cb.head]
-	cb.head = (cb.head + 1)  % cb.capacity
+	// release the reference held by the slot
+	cb.queue[cb.head] = zero
+	cb.head = (cb.head + 1) % cb.capacity
 	cb.length--
 
-	return value,nil
+	return value, nil
 }
 
-// String
-func (cb *CircularQueue) String() string {
-	return fmt.Sprintf("CircularQueue Len:%v Cap:%v",cb.Len(),cb.Cap())
+func (cb *CircularQueue[T]) Peek() (T, error) {
+	if cb.length == 0 {
+		var zero T
+		return zero, ErrEmpty
+	}
+	return cb.queue[cb.head], nil
+}
+
+// overflow applies the policy to a value pushed on a full queue.
+// a plain queue can't block, so Block is the same as Reject
+func (cb *CircularQueue[T]) overflow(value T) error {
+	switch {
+	case cb.capacity == 0:
+		return ErrFull
+
+	case cb.policy == OverflowDropOldest:
+		// overwrite the head, the ring moves on by one
+		oldest := cb.queue[cb.head]
+		cb.queue[cb.tail] = value
+		cb.head = (cb.head + 1) % cb.capacity
+		cb.tail = cb.head
+		cb.drop(oldest)
+		return nil
+
+	case cb.policy == OverflowDropNewest:
+		cb.drop(value)
+		return nil
+	}
+	return ErrFull
+}
+
+// drop counts an element lost to the policy and hands it to evict
+func (cb *CircularQueue[T]) drop(value T) {
+	cb.dropped++
+	if cb.evict != nil {
+		cb.evict(value)
+	}
+}
+
+// Dropped is the number of elements evicted or discarded
+// by the overflow policy
+func (cb *CircularQueue[T]) Dropped() int {
+	return cb.dropped
+}
+
+// overflowPolicy is the policy applied by Push
+func (cb *CircularQueue[T]) overflowPolicy() OverflowPolicy {
+	return cb.policy
+}
+
+// PeekN returns up to n elements from the head without removing them
+func (cb *CircularQueue[T]) PeekN(n int) []T {
+	n = max(0, min(n, cb.length))
+	values := make([]T, n)
+	c := copy(values, cb.queue[cb.head:])
+	copy(values[c:], cb.queue)
+	return values
+}
+
+// PushMany adds as many of values as fit in one pass
+// and returns the number added. the overflow policy
+// is left to the caller
+func (cb *CircularQueue[T]) PushMany(values []T) int {
+	n := min(len(values), cb.capacity-cb.length)
+	if n <= 0 {
+		return 0
+	}
+
+	// copy up to the end of the buffer, then wrap to the start
+	c := copy(cb.queue[cb.tail:], values[:n])
+	copy(cb.queue, values[c:n])
+	cb.tail = (cb.tail + n) % cb.capacity
+	cb.length += n
+
+	return n
 }
 
+// PopMany removes up to len(dst) elements into dst in one pass
+// and returns the number removed
+func (cb *CircularQueue[T]) PopMany(dst []T) int {
+	n := min(len(dst), cb.length)
+	if n <= 0 {
+		return 0
+	}
+
+	// copy up to the end of the buffer, then wrap to the start
+	c := copy(dst[:n], cb.queue[cb.head:])
+	copy(dst[c:n], cb.queue)
+
+	// release the references held by the slots
+	clear(cb.queue[cb.head : cb.head+c])
+	clear(cb.queue[:n-c])
 
-func NewCircularQueue(cap int) Queue {
-	var cq CircularQueue
+	cb.head = (cb.head + n) % cb.capacity
+	cb.length -= n
+
+	return n
+}
+
+// String
+func (cb *CircularQueue[T]) String() string {
+	return fmt.Sprintf("CircularQueue Len:%v Cap:%v", cb.Len(), cb.Cap())
+}
+
+// NewCircularQueueOf creates a circular buffer queue of elements of type T
+func NewCircularQueueOf[T any](cap int) Queue[T] {
+	var cq CircularQueue[T]
 
 	cq.length = 0
 	cq.capacity = cap
 	cq.head = 0
 	cq.tail = 0
-	cq.queue = make([]interface{},cap)
+	cq.queue = make([]T, cap)
 
 	return &cq
 }
 
-func NewSyncCircular(cap int) SynchronizedQueue {
-	var cq Queue
-	var bq SynchronizedQueue 
+// NewCircularQueueOverflowOf creates a circular buffer queue of elements
+// of type T whose Push handles a full queue with the given policy.
+// with OverflowDropOldest it is an overwriting ring.
+// evict, if not nil, is called with each element the policy drops
+func NewCircularQueueOverflowOf[T any](cap int, policy OverflowPolicy, evict func(value T)) Queue[T] {
+	cq := NewCircularQueueOf[T](cap).(*CircularQueue[T])
+
+	cq.policy = policy
+	cq.evict = evict
+
+	return cq
+}
+
+// NewSyncCircularOf wraps a typed circular buffer in a SynchronizedQueue
+func NewSyncCircularOf[T any](cap int) SynchronizedQueue[T] {
+	var cq Queue[T]
+	var bq SynchronizedQueue[T]
 
-	cq = NewCircularQueue(cap)
+	cq = NewCircularQueueOf[T](cap)
 
-	bq = NewSynchronizedQueue(cq) 
+	bq = NewSynchronizedQueueOf(cq)
 
 	return bq
 }
+
+func NewCircularQueue(cap int) Queue[interface{}] {
+	return NewCircularQueueOf[interface{}](cap)
+}
+
+func NewSyncCircular(cap int) SynchronizedQueue[interface{}] {
+	return NewSyncCircularOf[interface{}](cap)
+}
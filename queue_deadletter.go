@@ -0,0 +1,134 @@
+package queue
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// DeadLetter is an item a ReliableQueue gave up on
+type DeadLetter[T any] struct {
+	value    T
+	attempts int
+	reason   error
+}
+
+// Value is the item
+func (dl DeadLetter[T]) Value() T {
+	return dl.value
+}
+
+// Attempts is the number of times the item was delivered
+func (dl DeadLetter[T]) Attempts() int {
+	return dl.attempts
+}
+
+// Reason is why the last delivery failed, the reason given to Nack
+// or ErrTimeout if the visibility timeout ran out
+func (dl DeadLetter[T]) Reason() error {
+	return dl.reason
+}
+
+// String
+func (dl DeadLetter[T]) String() string {
+	return fmt.Sprintf("DeadLetter Value:%v Attempts:%v Reason:%v", dl.value, dl.attempts, dl.reason)
+}
+
+// deadLetter moves an item that failed its last allowed delivery
+// to the dead-letter queue. if that is full the item stays on this
+// queue. the caller must hold the mutex
+func (rq *ReliableQueue[T]) deadLetter(item reliableItem[T]) bool {
+	if rq.dead == nil || item.attempts < rq.maxAttempts {
+		return false
+	}
+	return rq.dead.TryPut(DeadLetter[T]{item.value, item.attempts, item.err}) == nil
+}
+
+// DeadLetters returns the items in the dead-letter queue
+// without removing them
+func (rq *ReliableQueue[T]) DeadLetters() []DeadLetter[T] {
+	if rq.dead == nil {
+		return []DeadLetter[T]{}
+	}
+
+	// lock the mutex
+	rq.mtx.Lock()
+	defer rq.mtx.Unlock()
+
+	return rq.dead.PeekN(math.MaxInt)
+}
+
+// Replay moves up to n items from the head of the dead-letter queue
+// to the tail of this one, where they start over with no attempts.
+// it stops early when this queue is full, and returns how many it moved.
+//
+// each item is put on this queue before it is taken off the dead-letter
+// queue, so nothing is lost if there is no room. that relies on nothing
+// else getting from the dead-letter queue: an item got from it in between
+// ends up in both queues
+func (rq *ReliableQueue[T]) Replay(n int) (int, error) {
+	if rq.dead == nil || n <= 0 {
+		return 0, nil
+	}
+
+	// lock the mutex, so the look and the move are one step
+	rq.mtx.Lock()
+	defer rq.mtx.Unlock()
+
+	for i := 0; i < n; i++ {
+		// look first, so nothing is lost if there is no room
+		dl, err := rq.dead.TryPeek()
+		if err != nil {
+			return i, ignoreEmpty(err)
+		}
+		if err := rq.queue.TryPut(dl.value); err != nil {
+			return i, err
+		}
+		if _, err := rq.dead.TryGet(); err != nil {
+			return i, err
+		}
+	}
+	return n, nil
+}
+
+// Purge empties the dead-letter queue and returns how many items it dropped
+func (rq *ReliableQueue[T]) Purge() int {
+	if rq.dead == nil {
+		return 0
+	}
+
+	// lock the mutex
+	rq.mtx.Lock()
+	defer rq.mtx.Unlock()
+
+	n := 0
+	for {
+		if _, err := rq.dead.TryGet(); err != nil {
+			return n
+		}
+		n++
+	}
+}
+
+// ignoreEmpty treats running out of items as the end of the work
+func ignoreEmpty(err error) error {
+	if errors.Is(err, ErrEmpty) || errors.Is(err, ErrClosed) {
+		return nil
+	}
+	return err
+}
+
+// NewReliableQueueDeadLetterOf wraps the backend q so its items are
+// delivered until they are acked, like NewReliableQueueOf. an item whose
+// maxAttempts-th delivery fails is moved to dead instead, which must be
+// another queue. the ReliableQueue takes dead over: use DeadLetters,
+// Replay and Purge rather than getting from it directly
+func NewReliableQueueDeadLetterOf[T any](q SynchronizedQueue[T], visibility time.Duration, maxAttempts int, dead SynchronizedQueue[DeadLetter[T]]) *ReliableQueue[T] {
+	rq := NewReliableQueueOf(q, visibility)
+
+	rq.maxAttempts = maxAttempts
+	rq.dead = dead
+
+	return rq
+}
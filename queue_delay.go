@@ -0,0 +1,495 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// delayItem is an element of a DelayQueue and the time it is due
+type delayItem[T any] struct {
+	value T
+	at    time.Time
+}
+
+// DelayQueue is a SynchronizedQueue whose elements only become visible
+// to Get once their scheduled time arrives. they are kept in a heap
+// ordered by that time, so Get sleeps exactly until the earliest one
+// is due. elements due at the same time are first come first served.
+//
+// Put schedules an element for now. Len and the bound count all the
+// elements, due or not, while TryGet, GetMany and the Peeks only see
+// the ones that are due. after Close the remaining elements are still
+// handed out by Get as they come due
+type DelayQueue[T any] struct {
+	queue  *PriorityFuncQueue[delayItem[T]] // elements in the order they are due
+	mtx    sync.Mutex                       // a mutex for mutual exclusion
+	putcv  *sync.Cond                       // a condition variable for controlling Puts
+	getcv  *sync.Cond                       // a condition variable for controlling Gets
+	closed bool                             // no more Puts are accepted once set
+}
+
+// PutAt adds an element that becomes visible at time at
+// if the queue is full the function blocks
+func (dq *DelayQueue[T]) PutAt(value T, at time.Time) error {
+	return dq.putAt(context.Background(), value, at, true)
+}
+
+// PutAfter adds an element that becomes visible after d
+// if the queue is full the function blocks
+func (dq *DelayQueue[T]) PutAfter(value T, d time.Duration) error {
+	return dq.PutAt(value, time.Now().Add(d))
+}
+
+// TryPutAt adds an element that becomes visible at time at
+// if the queue is full, an error is returned
+func (dq *DelayQueue[T]) TryPutAt(value T, at time.Time) error {
+	return dq.putAt(context.Background(), value, at, false)
+}
+
+// TryPutAfter adds an element that becomes visible after d
+// if the queue is full, an error is returned
+func (dq *DelayQueue[T]) TryPutAfter(value T, d time.Duration) error {
+	return dq.TryPutAt(value, time.Now().Add(d))
+}
+
+// PutAtContext adds an element that becomes visible at time at
+// if the queue is full the function blocks until there is room
+// or the context is done
+func (dq *DelayQueue[T]) PutAtContext(ctx context.Context, value T, at time.Time) error {
+	return dq.putAt(ctx, value, at, true)
+}
+
+// Put adds an element that is visible right away
+// if the queue is full the function blocks
+func (dq *DelayQueue[T]) Put(value T) error {
+	return dq.PutAt(value, time.Now())
+}
+
+// TryPut adds an element that is visible right away
+// if the queue is full, an error is returned
+func (dq *DelayQueue[T]) TryPut(value T) error {
+	return dq.TryPutAt(value, time.Now())
+}
+
+// PutContext adds an element that is visible right away
+// if the queue is full the function blocks until there is room
+// or the context is done
+func (dq *DelayQueue[T]) PutContext(ctx context.Context, value T) error {
+	return dq.putAt(ctx, value, time.Now(), true)
+}
+
+// PutTimeout adds an element that is visible right away
+// if the queue is full the function blocks for at most d
+// and returns ErrTimeout if there is still no room
+func (dq *DelayQueue[T]) PutTimeout(value T, d time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+
+	return timeoutError(dq.PutContext(ctx, value))
+}
+
+// putAt schedules value at time at, waiting for room if block is set
+func (dq *DelayQueue[T]) putAt(ctx context.Context, value T, at time.Time, block bool) error {
+	// lock the mutex
+	dq.mtx.Lock()
+	defer dq.mtx.Unlock()
+
+	// is queue full ?
+	full := func() bool { return dq.queue.Len() >= dq.queue.Cap() }
+	if full() && !dq.closed && !block {
+		return ErrFull
+	}
+
+	// wake the waiters if the context is done while blocked
+	if full() && ctx.Done() != nil {
+		stop := context.AfterFunc(ctx, broadcaster(dq.putcv))
+		defer stop()
+	}
+
+	// block until there is room in the queue
+	for full() && !dq.closed {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		// release and wait
+		dq.putcv.Wait()
+	}
+
+	// Close wakes up all blocked Puts
+	if dq.closed {
+		return ErrClosed
+	}
+
+	// the Gets wait for the current head, so they only
+	// need waking if the new element comes due before it
+	head, empty := dq.queue.Peek()
+	if err := dq.queue.Push(delayItem[T]{value, at}); err != nil {
+		return err
+	}
+	if empty != nil || at.Before(head.at) {
+		dq.getcv.Broadcast()
+	}
+
+	return nil
+}
+
+// due returns the head of the queue if its time has come,
+// or else how long it is until then. the caller must hold the mutex
+func (dq *DelayQueue[T]) due() (delayItem[T], time.Duration, error) {
+	head, err := dq.queue.Peek()
+	if err != nil {
+		return head, 0, err
+	}
+	return head, time.Until(head.at), nil
+}
+
+// waitDue blocks until the head of the queue is due and returns it
+// without removing it, or until the context is done.
+// the caller must hold the mutex
+func (dq *DelayQueue[T]) waitDue(ctx context.Context) (delayItem[T], error) {
+	// wake the waiters if the context is done while blocked
+	if ctx.Done() != nil {
+		stop := context.AfterFunc(ctx, broadcaster(dq.getcv))
+		defer stop()
+	}
+
+	for {
+		head, wait, err := dq.due()
+		if err == nil && wait <= 0 {
+			return head, nil
+		}
+
+		// closed and nothing left to drain
+		if err != nil && dq.closed {
+			return head, ErrClosed
+		}
+
+		if cerr := ctx.Err(); cerr != nil {
+			return head, cerr
+		}
+
+		// sleep until the head is due. an earlier Put or Close wakes us sooner
+		if err == nil {
+			timer := time.AfterFunc(wait, broadcaster(dq.getcv))
+			dq.getcv.Wait()
+			timer.Stop()
+		} else {
+			dq.getcv.Wait()
+		}
+	}
+}
+
+// Get returns the element that is due first
+// if no element is due the caller blocks until one is
+// once the queue is closed and drained ErrClosed is returned
+func (dq *DelayQueue[T]) Get() (T, error) {
+	// a background context is never done
+	return dq.GetContext(context.Background())
+}
+
+// GetContext returns the element that is due first
+// if no element is due the caller blocks until one is
+// or the context is done
+func (dq *DelayQueue[T]) GetContext(ctx context.Context) (T, error) {
+	// lock the mutex
+	dq.mtx.Lock()
+	defer dq.mtx.Unlock()
+
+	if _, err := dq.waitDue(ctx); err != nil {
+		var zero T
+		return zero, err
+	}
+
+	return dq.pop()
+}
+
+// GetTimeout returns the element that is due first
+// if no element is due the function blocks for at most d
+// and returns ErrTimeout if there is still none
+func (dq *DelayQueue[T]) GetTimeout(d time.Duration) (T, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+
+	value, err := dq.GetContext(ctx)
+	return value, timeoutError(err)
+}
+
+// TryGet returns the element that is due first
+// if no element is due an error is returned
+func (dq *DelayQueue[T]) TryGet() (T, error) {
+	var zero T
+
+	// lock the mutex
+	dq.mtx.Lock()
+	defer dq.mtx.Unlock()
+
+	_, wait, err := dq.due()
+	if err != nil {
+		if dq.closed {
+			return zero, ErrClosed
+		}
+		return zero, ErrEmpty
+	}
+	if wait > 0 {
+		return zero, ErrEmpty
+	}
+
+	return dq.pop()
+}
+
+// pop removes the head and wakes a Put. the caller must hold the mutex
+func (dq *DelayQueue[T]) pop() (T, error) {
+	item, err := dq.queue.Pop()
+	if err != nil {
+		return item.value, err
+	}
+
+	// signal a Put to wake up
+	dq.putcv.Signal()
+
+	return item.value, nil
+}
+
+// PutMany adds as many of values as there is room for,
+// all visible right away. ErrFull is returned if not all of them fit
+func (dq *DelayQueue[T]) PutMany(values []T) (int, error) {
+	for i, value := range values {
+		if err := dq.TryPut(value); err != nil {
+			return i, err
+		}
+	}
+	return len(values), nil
+}
+
+// PutManyContext adds values, all visible right away
+// if the queue is full the function blocks until there is room for
+// at least one of them or the context is done
+func (dq *DelayQueue[T]) PutManyContext(ctx context.Context, values []T) (int, error) {
+	if len(values) == 0 {
+		return 0, nil
+	}
+
+	// block for the first one only
+	if err := dq.PutContext(ctx, values[0]); err != nil {
+		return 0, err
+	}
+
+	n, err := dq.PutMany(values[1:])
+	if errors.Is(err, ErrFull) {
+		err = nil
+	}
+	return n + 1, err
+}
+
+// GetMany copies up to max due elements into dst
+// ErrEmpty is returned if none were due
+func (dq *DelayQueue[T]) GetMany(dst []T, max int) (int, error) {
+	var err error
+
+	_, max = batchLimits(len(dst), 0, max)
+	if max == 0 {
+		return 0, nil
+	}
+
+	n := 0
+	for n < max {
+		dst[n], err = dq.TryGet()
+		if err != nil {
+			break
+		}
+		n++
+	}
+
+	if n > 0 {
+		return n, nil
+	}
+	return 0, err
+}
+
+// GetManyContext copies up to max due elements into dst.
+// the function blocks until min elements have come due
+// or the context is done, in which case the elements already
+// received are returned along with the error
+func (dq *DelayQueue[T]) GetManyContext(ctx context.Context, dst []T, min, max int) (int, error) {
+	var err error
+
+	min, max = batchLimits(len(dst), min, max)
+
+	n := 0
+	for n < min {
+		dst[n], err = dq.GetContext(ctx)
+		if err != nil {
+			// a closed queue returns whatever was left
+			if errors.Is(err, ErrClosed) && n > 0 {
+				err = nil
+			}
+			return n, err
+		}
+		n++
+	}
+
+	m, _ := dq.GetMany(dst[n:], max-n)
+	return n + m, nil
+}
+
+// Peek returns the element that is due first without removing it
+// if no element is due the caller blocks until one is
+// once the queue is closed and drained ErrClosed is returned
+func (dq *DelayQueue[T]) Peek() (T, error) {
+	// lock the mutex
+	dq.mtx.Lock()
+	defer dq.mtx.Unlock()
+
+	head, err := dq.waitDue(context.Background())
+	return head.value, err
+}
+
+// TryPeek returns the element that is due first without removing it
+// if no element is due an error is returned
+func (dq *DelayQueue[T]) TryPeek() (T, error) {
+	var zero T
+
+	// lock the mutex
+	dq.mtx.Lock()
+	defer dq.mtx.Unlock()
+
+	head, wait, err := dq.due()
+	if err != nil {
+		if dq.closed {
+			return zero, ErrClosed
+		}
+		return zero, ErrEmpty
+	}
+	if wait > 0 {
+		return zero, ErrEmpty
+	}
+	return head.value, nil
+}
+
+// PeekN returns up to n of the due elements without removing them
+// in the order Get would return them
+func (dq *DelayQueue[T]) PeekN(n int) []T {
+	// lock the mutex
+	dq.mtx.Lock()
+	defer dq.mtx.Unlock()
+
+	now := time.Now()
+	items := dq.queue.PeekN(n)
+	values := make([]T, 0, len(items))
+	for _, item := range items {
+		if item.at.After(now) {
+			break
+		}
+		values = append(values, item.value)
+	}
+	return values
+}
+
+// snapshot returns all the elements, due or not, in the order
+// they come due, and when each one is due
+func (dq *DelayQueue[T]) snapshot() ([]T, []time.Time, snapshotTimes) {
+	// lock the mutex
+	dq.mtx.Lock()
+	defer dq.mtx.Unlock()
+
+	items := dq.queue.PeekN(dq.queue.Len())
+	values := make([]T, len(items))
+	due := make([]time.Time, len(items))
+	for i, item := range items {
+		values[i] = item.value
+		due[i] = item.at
+	}
+	return values, due, timesDue
+}
+
+// restore adds all the elements of a snapshot or none of them
+// if there isn't room. each one is due at its time if the snapshot
+// has due times, and at once if it doesn't
+func (dq *DelayQueue[T]) restore(values []T, times []time.Time, kind snapshotTimes) (int, error) {
+	// lock the mutex
+	dq.mtx.Lock()
+	defer dq.mtx.Unlock()
+
+	// is queue closed ?
+	if dq.closed {
+		return 0, ErrClosed
+	}
+
+	// is there room for all of them ?
+	if len(values) > dq.queue.Cap()-dq.queue.Len() {
+		return 0, ErrFull
+	}
+
+	now := time.Now()
+	for i, value := range values {
+		at := now
+		if kind == timesDue {
+			at = times[i]
+		}
+		if err := dq.queue.Push(delayItem[T]{value, at}); err != nil {
+			return i, err
+		}
+	}
+
+	// the head may have changed, so every Get looks again
+	dq.getcv.Broadcast()
+
+	return len(values), nil
+}
+
+// Len is the current number of elements in the queue, due or not
+func (dq *DelayQueue[T]) Len() int {
+	// lock the mutex
+	dq.mtx.Lock()
+	defer dq.mtx.Unlock()
+
+	return dq.queue.Len()
+}
+
+// Cap is the maximum number of elements the queue can hold
+func (dq *DelayQueue[T]) Cap() int {
+	return dq.queue.Cap()
+}
+
+// Close stops the queue from accepting more elements
+// and wakes up every blocked Put and Get.
+// elements already in the queue can still be got once they are due
+func (dq *DelayQueue[T]) Close() {
+	dq.mtx.Lock()
+	defer dq.mtx.Unlock()
+
+	dq.closed = true
+	dq.putcv.Broadcast()
+	dq.getcv.Broadcast()
+}
+
+// String
+func (dq *DelayQueue[T]) String() string {
+	return fmt.Sprintf("DelayQueue Len:%v Cap:%v", dq.Len(), dq.Cap())
+}
+
+// NewDelayQueueOf is a factory for creating delay queues
+// of elements of type T
+func NewDelayQueueOf[T any](cap int) *DelayQueue[T] {
+	var dq DelayQueue[T]
+
+	// a heap ordered by the time the elements are due
+	dq.queue = NewPriorityQueueFunc(cap, func(a, b delayItem[T]) bool {
+		return a.at.Before(b.at)
+	}).(*PriorityFuncQueue[delayItem[T]])
+
+	// both condition variables get the same mutex
+	// but wakeups go from put to get and vice versa
+	dq.putcv = sync.NewCond(&dq.mtx)
+	dq.getcv = sync.NewCond(&dq.mtx)
+
+	return &dq
+}
+
+// NewDelayQueue is a factory for creating delay queues
+func NewDelayQueue(cap int) *DelayQueue[interface{}] {
+	return NewDelayQueueOf[interface{}](cap)
+}
@@ -0,0 +1,179 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+)
+
+// CircularDeque is a CircularQueue that can also be pushed at the head
+// and popped at the tail. as a Queue it is first in first out:
+// Push adds at the back and Pop removes from the front
+type CircularDeque[T any] struct {
+	CircularQueue[T]
+}
+
+// PushFront adds an element at the head, ahead of the others
+func (cd *CircularDeque[T]) PushFront(value T) error {
+	if cd.length >= cd.capacity {
+		return ErrFull
+	}
+	// step the head back and insert
+	cd.head = (cd.head - 1 + cd.capacity) % cd.capacity
+	cd.queue[cd.head] = value
+	cd.length++
+
+	return nil
+}
+
+// PopBack removes the element at the tail, the last one pushed at the back
+func (cd *CircularDeque[T]) PopBack() (T, error) {
+	var zero T
+
+	if cd.length == 0 {
+		return zero, ErrEmpty
+	}
+	cd.tail = (cd.tail - 1 + cd.capacity) % cd.capacity
+	value := cd.queue[cd.tail]
+	// release the reference held by the slot
+	cd.queue[cd.tail] = zero
+	cd.length--
+
+	return value, nil
+}
+
+// PeekBack returns the element at the tail without removing it
+func (cd *CircularDeque[T]) PeekBack() (T, error) {
+	if cd.length == 0 {
+		var zero T
+		return zero, ErrEmpty
+	}
+	return cd.queue[(cd.tail-1+cd.capacity)%cd.capacity], nil
+}
+
+// String
+func (cd *CircularDeque[T]) String() string {
+	return fmt.Sprintf("CircularDeque Len:%v Cap:%v", cd.Len(), cd.Cap())
+}
+
+// NewCircularDequeOf creates a circular buffer deque of elements of type T
+func NewCircularDequeOf[T any](cap int) *CircularDeque[T] {
+	var cd CircularDeque[T]
+
+	cd.capacity = cap
+	cd.queue = make([]T, cap)
+
+	return &cd
+}
+
+// Deque is a CircularDeque wrapped in a SynchronizedQueue.
+// Put and Get are PushBack and PopFront, so it can stand in for
+// any SynchronizedQueue, and the other end is there for putting
+// an element back at the head or taking the newest one
+type Deque[T any] struct {
+	*SynchronizedQueueImpl[T]
+	deque *CircularDeque[T] // the backend, for the operations at the other end
+}
+
+// PushFront adds an element at the head
+// if the deque is full the function blocks
+func (dq *Deque[T]) PushFront(value T) error {
+	return dq.PushFrontContext(context.Background(), value)
+}
+
+// PushFrontContext adds an element at the head
+// if the deque is full the function blocks until there is room
+// or the context is done
+func (dq *Deque[T]) PushFrontContext(ctx context.Context, value T) error {
+	return dq.put(ctx, value, dq.deque.PushFront)
+}
+
+// TryPushFront adds an element at the head
+// if the deque is full, an error is returned
+func (dq *Deque[T]) TryPushFront(value T) error {
+	return dq.tryPut(value, dq.deque.PushFront)
+}
+
+// PushBack adds an element at the tail, the same as Put
+// if the deque is full the function blocks
+func (dq *Deque[T]) PushBack(value T) error {
+	return dq.Put(value)
+}
+
+// PushBackContext adds an element at the tail, the same as PutContext
+func (dq *Deque[T]) PushBackContext(ctx context.Context, value T) error {
+	return dq.PutContext(ctx, value)
+}
+
+// TryPushBack adds an element at the tail, the same as TryPut
+// if the deque is full, an error is returned
+func (dq *Deque[T]) TryPushBack(value T) error {
+	return dq.TryPut(value)
+}
+
+// PopFront removes the element at the head, the same as Get
+// if the deque is empty the function blocks
+func (dq *Deque[T]) PopFront() (T, error) {
+	return dq.Get()
+}
+
+// PopFrontContext removes the element at the head, the same as GetContext
+func (dq *Deque[T]) PopFrontContext(ctx context.Context) (T, error) {
+	return dq.GetContext(ctx)
+}
+
+// TryPopFront removes the element at the head, the same as TryGet
+// if the deque is empty, an error is returned
+func (dq *Deque[T]) TryPopFront() (T, error) {
+	return dq.TryGet()
+}
+
+// PopBack removes the element at the tail
+// if the deque is empty the function blocks
+func (dq *Deque[T]) PopBack() (T, error) {
+	return dq.PopBackContext(context.Background())
+}
+
+// PopBackContext removes the element at the tail
+// if the deque is empty the function blocks until there is an element
+// or the context is done
+func (dq *Deque[T]) PopBackContext(ctx context.Context) (T, error) {
+	return dq.get(ctx, dq.deque.PopBack)
+}
+
+// TryPopBack removes the element at the tail
+// if the deque is empty, an error is returned
+func (dq *Deque[T]) TryPopBack() (T, error) {
+	return dq.tryGet(dq.deque.PopBack)
+}
+
+// TryPeekBack returns the element at the tail without removing it
+// if the deque is empty, an error is returned
+func (dq *Deque[T]) TryPeekBack() (T, error) {
+	// lock the mutex
+	dq.mtx.Lock()
+	defer dq.mtx.Unlock()
+
+	value, err := dq.deque.PeekBack()
+	if err != nil && dq.closed {
+		return value, ErrClosed
+	}
+	return value, err
+}
+
+// NewDequeOf creates a synchronized circular buffer deque
+// of elements of type T
+func NewDequeOf[T any](cap int) *Deque[T] {
+	var dq Deque[T]
+
+	// create the backend
+	dq.deque = NewCircularDequeOf[T](cap)
+
+	// wrap it in the synchronized bounded queue
+	dq.SynchronizedQueueImpl = newSynchronizedQueueImpl[T](dq.deque)
+
+	return &dq
+}
+
+func NewDeque(cap int) *Deque[interface{}] {
+	return NewDequeOf[interface{}](cap)
+}
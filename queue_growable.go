@@ -0,0 +1,199 @@
+package queue
+
+import (
+	"fmt"
+	"math"
+)
+
+// Unbounded is reported by Cap for a queue that has no bound,
+// so a SynchronizedQueue wrapping it never blocks a Put
+const Unbounded = math.MaxInt
+
+// the buffer of a GrowableQueue never shrinks below this
+const growableMinSize = 16
+
+// GrowableQueue is a Queue backed by a circular buffer that
+// doubles when it is full and halves when it is a quarter full,
+// so the memory taken by a burst is given back once it is drained.
+// the limit is a soft bound on the number of elements, Unbounded for none
+type GrowableQueue[T any] struct {
+	queue  []T // data, len(queue) is the current buffer size
+	head   int // items are pulled from the head
+	tail   int // items are pushed to the tail
+	length int // current number of elements in the queue
+	limit  int // maximum allowed elements total
+}
+
+func (gq *GrowableQueue[T]) Len() int {
+	return gq.length
+}
+
+func (gq *GrowableQueue[T]) Cap() int {
+	return gq.limit
+}
+
+func (gq *GrowableQueue[T]) Push(value T) error {
+	if gq.length >= gq.limit {
+		return ErrFull
+	}
+	if gq.length == len(gq.queue) {
+		gq.resize(2 * len(gq.queue))
+	}
+
+	// insert and count
+	gq.queue[gq.tail] = value
+	gq.tail = (gq.tail + 1) % len(gq.queue)
+	gq.length++
+
+	return nil
+}
+
+func (gq *GrowableQueue[T]) Pop() (T, error) {
+	var zero T
+
+	if gq.length == 0 {
+		return zero, ErrEmpty
+	}
+	value := gq.queue[gq.head]
+	// release the reference held by the slot
+	gq.queue[gq.head] = zero
+	gq.head = (gq.head + 1) % len(gq.queue)
+	gq.length--
+
+	gq.shrink()
+
+	return value, nil
+}
+
+func (gq *GrowableQueue[T]) Peek() (T, error) {
+	if gq.length == 0 {
+		var zero T
+		return zero, ErrEmpty
+	}
+	return gq.queue[gq.head], nil
+}
+
+// PeekN returns up to n elements from the head without removing them
+func (gq *GrowableQueue[T]) PeekN(n int) []T {
+	n = max(0, min(n, gq.length))
+	values := make([]T, n)
+	gq.copyOut(values)
+	return values
+}
+
+// PushMany adds as many of values as fit under the limit
+// growing the buffer at most once, and returns the number added
+func (gq *GrowableQueue[T]) PushMany(values []T) int {
+	n := min(len(values), gq.limit-gq.length)
+	if n <= 0 {
+		return 0
+	}
+
+	size := len(gq.queue)
+	for size < gq.length+n {
+		size *= 2
+	}
+	if size != len(gq.queue) {
+		gq.resize(size)
+	}
+
+	// copy up to the end of the buffer, then wrap to the start
+	c := copy(gq.queue[gq.tail:], values[:n])
+	copy(gq.queue, values[c:n])
+	gq.tail = (gq.tail + n) % len(gq.queue)
+	gq.length += n
+
+	return n
+}
+
+// PopMany removes up to len(dst) elements into dst
+// and returns the number removed
+func (gq *GrowableQueue[T]) PopMany(dst []T) int {
+	n := min(len(dst), gq.length)
+	if n <= 0 {
+		return 0
+	}
+
+	// copy up to the end of the buffer, then wrap to the start
+	c := gq.copyOut(dst[:n])
+
+	// release the references held by the slots
+	clear(gq.queue[gq.head : gq.head+c])
+	clear(gq.queue[:n-c])
+
+	gq.head = (gq.head + n) % len(gq.queue)
+	gq.length -= n
+
+	gq.shrink()
+
+	return n
+}
+
+// copyOut copies elements from the head into dst, which must not be
+// longer than the queue, and returns the number taken before the wrap
+func (gq *GrowableQueue[T]) copyOut(dst []T) int {
+	c := copy(dst, gq.queue[gq.head:])
+	copy(dst[c:], gq.queue)
+	return c
+}
+
+// shrink halves the buffer until it is more than a quarter full
+func (gq *GrowableQueue[T]) shrink() {
+	size := len(gq.queue)
+	for size > growableMinSize && gq.length <= size/4 {
+		size /= 2
+	}
+	if size != len(gq.queue) {
+		gq.resize(size)
+	}
+}
+
+// resize moves the elements to the start of a new buffer of the given size
+func (gq *GrowableQueue[T]) resize(size int) {
+	queue := make([]T, size)
+	gq.copyOut(queue[:gq.length])
+
+	gq.queue = queue
+	gq.head = 0
+	gq.tail = gq.length % size
+}
+
+// String
+func (gq *GrowableQueue[T]) String() string {
+	if gq.limit == Unbounded {
+		return fmt.Sprintf("GrowableQueue Len:%v Cap:Unbounded", gq.Len())
+	}
+	return fmt.Sprintf("GrowableQueue Len:%v Cap:%v", gq.Len(), gq.Cap())
+}
+
+// NewGrowableQueueOf creates a growable circular buffer queue
+// of elements of type T. limit is a soft bound on the number
+// of elements, use Unbounded for a queue that never fills up
+func NewGrowableQueueOf[T any](limit int) Queue[T] {
+	var gq GrowableQueue[T]
+
+	gq.limit = limit
+	gq.queue = make([]T, growableMinSize)
+
+	return &gq
+}
+
+// NewSyncGrowableOf wraps a typed growable queue in a SynchronizedQueue
+func NewSyncGrowableOf[T any](limit int) SynchronizedQueue[T] {
+	var gq Queue[T]
+	var bq SynchronizedQueue[T]
+
+	gq = NewGrowableQueueOf[T](limit)
+
+	bq = NewSynchronizedQueueOf(gq)
+
+	return bq
+}
+
+func NewGrowableQueue(limit int) Queue[interface{}] {
+	return NewGrowableQueueOf[interface{}](limit)
+}
+
+func NewSyncGrowable(limit int) SynchronizedQueue[interface{}] {
+	return NewSyncGrowableOf[interface{}](limit)
+}
@@ -2,67 +2,102 @@ package queue
 
 import (
 	"container/list"
-	"errors"
 	"fmt"
 )
 
 // ListQueue backed by container/list
 // the container/list data structure supports the semantics and methods
 // needed for the Queue interface, with the exception of a capacity.
-type ListQueue struct {
-	list *list.List	 // contains the elements currently in the queue
-	capacity int	     // maximum number of elements the queue can hold
+// container/list stores interface{} so elements are still boxed internally
+type ListQueue[T any] struct {
+	list     *list.List // contains the elements currently in the queue
+	capacity int        // maximum number of elements the queue can hold
 }
 
-func (lq *ListQueue) Len() int {
+func (lq *ListQueue[T]) Len() int {
 	return lq.list.Len()
 }
 
-func (lq *ListQueue) Cap() int {
+func (lq *ListQueue[T]) Cap() int {
 	return lq.capacity
 }
 
-func (lq *ListQueue) Push(value interface{}) error {
+func (lq *ListQueue[T]) Push(value T) error {
 	if lq.list.Len() >= lq.capacity {
-		return errors.New("queue is full")
+		return ErrFull
 	}
-	// insert 
+	// insert
 	lq.list.PushBack(value)
 
 	return nil
 }
 
-func (lq *ListQueue) Pop() (interface{}, error) {
+func (lq *ListQueue[T]) Pop() (T, error) {
 	if lq.list.Len() == 0 {
-		return nil, errors.New("queue is empty)")
+		var zero T
+		return zero, ErrEmpty
 	}
 
-	value := lq.list.Remove(lq.list.Front())
+	// a nil interface{} element yields the zero value
+	value, _ := lq.list.Remove(lq.list.Front()).(T)
 
-	return value,nil
+	return value, nil
 }
 
-// String
-func (lq *ListQueue)  String() string {
-	return fmt.Sprintf("ListQueue Len:%v Cap:%v",lq.Len(),lq.Cap())
+func (lq *ListQueue[T]) Peek() (T, error) {
+	if lq.list.Len() == 0 {
+		var zero T
+		return zero, ErrEmpty
+	}
+
+	// a nil interface{} element yields the zero value
+	value, _ := lq.list.Front().Value.(T)
+
+	return value, nil
+}
+
+// PeekN returns up to n elements from the head without removing them
+func (lq *ListQueue[T]) PeekN(n int) []T {
+	n = max(0, min(n, lq.list.Len()))
+	values := make([]T, n)
+	e := lq.list.Front()
+	for i := 0; i < n; i++ {
+		values[i], _ = e.Value.(T)
+		e = e.Next()
+	}
+	return values
 }
 
+// String
+func (lq *ListQueue[T]) String() string {
+	return fmt.Sprintf("ListQueue Len:%v Cap:%v", lq.Len(), lq.Cap())
+}
 
-func NewListQueue(cap int) Queue {
-	var lq ListQueue
+// NewListQueueOf creates a container/list queue of elements of type T
+func NewListQueueOf[T any](cap int) Queue[T] {
+	var lq ListQueue[T]
 	lq.capacity = cap
 	lq.list = list.New()
 
 	return &lq
 }
 
-func NewSyncList(cap int) SynchronizedQueue {
-	var lq Queue
-	var bq SynchronizedQueue 
+// NewSyncListOf wraps a typed list queue in a SynchronizedQueue
+func NewSyncListOf[T any](cap int) SynchronizedQueue[T] {
+	var lq Queue[T]
+	var bq SynchronizedQueue[T]
 
-	lq = NewListQueue(cap)
+	lq = NewListQueueOf[T](cap)
 
-	bq = NewSynchronizedQueue(lq) 
+	bq = NewSynchronizedQueueOf(lq)
 
 	return bq
 }
+
+func NewListQueue(cap int) Queue[interface{}] {
+	return NewListQueueOf[interface{}](cap)
+}
+
+func NewSyncList(cap int) SynchronizedQueue[interface{}] {
+	return NewSyncListOf[interface{}](cap)
+}
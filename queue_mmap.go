@@ -0,0 +1,376 @@
+package queue
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+)
+
+// RecordCodec turns elements of type T into fixed-size records and back
+type RecordCodec[T any] interface {
+	// the number of bytes in a record
+	Size() int
+
+	// encode value into dst, which is Size() bytes
+	Put(dst []byte, value T) error
+
+	// decode a record of Size() bytes
+	Get(src []byte) T
+}
+
+// BinaryRecord encodes elements with encoding/binary, little endian.
+// T must have a fixed size: numbers, bools, and arrays and structs of them
+type BinaryRecord[T any] struct{}
+
+func (BinaryRecord[T]) Size() int {
+	var zero T
+	return binary.Size(zero)
+}
+
+func (BinaryRecord[T]) Put(dst []byte, value T) error {
+	return binary.Write(&recordWriter{dst: dst}, binary.LittleEndian, value)
+}
+
+func (BinaryRecord[T]) Get(src []byte) T {
+	var value T
+	binary.Read(bytes.NewReader(src), binary.LittleEndian, &value)
+	return value
+}
+
+// recordWriter writes into a record in place, and fails
+// rather than write past its end
+type recordWriter struct {
+	dst []byte
+	n   int // bytes written so far
+}
+
+func (rw *recordWriter) Write(p []byte) (int, error) {
+	if len(p) > len(rw.dst)-rw.n {
+		return 0, io.ErrShortBuffer
+	}
+	rw.n += copy(rw.dst[rw.n:], p)
+	return len(p), nil
+}
+
+// IntRecord stores an int as 8 bytes, little endian
+type IntRecord struct{}
+
+func (IntRecord) Size() int { return 8 }
+func (IntRecord) Put(dst []byte, value int) error {
+	binary.LittleEndian.PutUint64(dst, uint64(value))
+	return nil
+}
+func (IntRecord) Get(src []byte) int { return int(binary.LittleEndian.Uint64(src)) }
+
+// layout of the file. two copies of the header are written in turn,
+// so one of them is whole if the process dies while writing the other.
+// each is
+//
+//	0  magic
+//	8  generation, the newer valid copy wins
+//	16 record size
+//	24 capacity
+//	32 head
+//	40 tail
+//	48 length
+//	56 crc32 of bytes 0 to 56
+//
+// and the records follow the two copies
+const (
+	mmapMagic  = "GOQMMAP\x01"
+	mmapHeader = 64
+	mmapData   = 2 * mmapHeader
+)
+
+// MmapQueue is a CircularQueue of fixed-size records whose buffer is
+// a memory mapped file, so what is in the queue survives the process
+// and another process can read it. the position of the head and the
+// tail is kept in a header in the file, with a checksum checked on open.
+//
+// one process at a time may use the file
+type MmapQueue[T any] struct {
+	file     *os.File
+	data     []byte // the mapping, header and records
+	codec    RecordCodec[T]
+	size     int    // bytes in a record
+	head     int    // items are pulled from the head
+	tail     int    // items are pushed to the tail
+	length   int    // current number of elements in the queue
+	capacity int    // maximum allowed elements total
+	gen      uint64 // generation of the last header written
+}
+
+func (mq *MmapQueue[T]) Len() int {
+	return mq.length
+}
+
+func (mq *MmapQueue[T]) Cap() int {
+	return mq.capacity
+}
+
+// record is the slot of element i of the buffer
+func (mq *MmapQueue[T]) record(i int) []byte {
+	offset := mmapData + i*mq.size
+	return mq.data[offset : offset+mq.size]
+}
+
+func (mq *MmapQueue[T]) Push(value T) error {
+	if mq.data == nil {
+		return ErrClosed
+	}
+	if mq.length >= mq.capacity {
+		return ErrFull
+	}
+	// write the record, then the header that takes it in
+	if err := mq.codec.Put(mq.record(mq.tail), value); err != nil {
+		return err
+	}
+	mq.tail = (mq.tail + 1) % mq.capacity
+	mq.length++
+	mq.commit()
+
+	return nil
+}
+
+func (mq *MmapQueue[T]) Pop() (T, error) {
+	var zero T
+
+	if mq.data == nil {
+		return zero, ErrClosed
+	}
+	if mq.length == 0 {
+		return zero, ErrEmpty
+	}
+	value := mq.codec.Get(mq.record(mq.head))
+	mq.head = (mq.head + 1) % mq.capacity
+	mq.length--
+	mq.commit()
+
+	return value, nil
+}
+
+func (mq *MmapQueue[T]) Peek() (T, error) {
+	var zero T
+
+	if mq.data == nil {
+		return zero, ErrClosed
+	}
+	if mq.length == 0 {
+		return zero, ErrEmpty
+	}
+	return mq.codec.Get(mq.record(mq.head)), nil
+}
+
+// PeekN returns up to n elements from the head without removing them
+func (mq *MmapQueue[T]) PeekN(n int) []T {
+	if mq.data == nil {
+		return nil
+	}
+	n = max(0, min(n, mq.length))
+	values := make([]T, n)
+	for i := range values {
+		values[i] = mq.codec.Get(mq.record((mq.head + i) % mq.capacity))
+	}
+	return values
+}
+
+// commit writes the header over the older of the two copies
+func (mq *MmapQueue[T]) commit() {
+	mq.gen++
+	h := mq.data[(mq.gen%2)*mmapHeader:][:mmapHeader]
+
+	copy(h, mmapMagic)
+	binary.LittleEndian.PutUint64(h[8:], mq.gen)
+	binary.LittleEndian.PutUint64(h[16:], uint64(mq.size))
+	binary.LittleEndian.PutUint64(h[24:], uint64(mq.capacity))
+	binary.LittleEndian.PutUint64(h[32:], uint64(mq.head))
+	binary.LittleEndian.PutUint64(h[40:], uint64(mq.tail))
+	binary.LittleEndian.PutUint64(h[48:], uint64(mq.length))
+	binary.LittleEndian.PutUint32(h[56:], crc32.ChecksumIEEE(h[:56]))
+}
+
+// load reads back the newer of the two header copies that is whole
+func (mq *MmapQueue[T]) load() error {
+	found := false
+	for i := 0; i < 2; i++ {
+		h := mq.data[i*mmapHeader:][:mmapHeader]
+		if string(h[:8]) != mmapMagic || crc32.ChecksumIEEE(h[:56]) != binary.LittleEndian.Uint32(h[56:]) {
+			continue
+		}
+		gen := binary.LittleEndian.Uint64(h[8:])
+		if found && gen < mq.gen {
+			continue
+		}
+		found = true
+
+		mq.gen = gen
+		mq.size = int(binary.LittleEndian.Uint64(h[16:]))
+		mq.capacity = int(binary.LittleEndian.Uint64(h[24:]))
+		mq.head = int(binary.LittleEndian.Uint64(h[32:]))
+		mq.tail = int(binary.LittleEndian.Uint64(h[40:]))
+		mq.length = int(binary.LittleEndian.Uint64(h[48:]))
+	}
+
+	if !found {
+		return fmt.Errorf("%w: no valid header", ErrCorrupt)
+	}
+	if mq.capacity <= 0 || mq.size <= 0 || len(mq.data) != mmapData+mq.capacity*mq.size ||
+		mq.head >= mq.capacity || mq.tail >= mq.capacity || mq.length > mq.capacity ||
+		(mq.head+mq.length)%mq.capacity != mq.tail {
+		return fmt.Errorf("%w: header doesn't match the file", ErrCorrupt)
+	}
+	return nil
+}
+
+// Sync flushes the file to disk. without it the contents survive
+// the process but not the machine
+func (mq *MmapQueue[T]) Sync() error {
+	if mq.data == nil {
+		return ErrClosed
+	}
+	return mq.file.Sync()
+}
+
+// Close unmaps and closes the file. the queue can't be used after that
+func (mq *MmapQueue[T]) Close() error {
+	if mq.data == nil {
+		return nil
+	}
+
+	err := munmap(mq.data)
+	mq.data = nil
+	if cerr := mq.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// String
+func (mq *MmapQueue[T]) String() string {
+	return fmt.Sprintf("MmapQueue Len:%v Cap:%v", mq.Len(), mq.Cap())
+}
+
+// OpenMmapQueueOf opens the memory mapped queue in the file at path,
+// creating it with room for cap records if it doesn't exist.
+// codec is BinaryRecord[T] when nil. an existing file must have been
+// created with the same capacity and record size
+func OpenMmapQueueOf[T any](path string, cap int, codec RecordCodec[T]) (*MmapQueue[T], error) {
+	var mq MmapQueue[T]
+
+	if codec == nil {
+		codec = BinaryRecord[T]{}
+	}
+	mq.codec = codec
+	mq.size = codec.Size()
+	if mq.size <= 0 {
+		var zero T
+		return nil, fmt.Errorf("%w: %T doesn't have a fixed size", ErrType, zero)
+	}
+	if cap <= 0 {
+		return nil, fmt.Errorf("mmap queue capacity must be positive, not %d", cap)
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	// a new file is sized for the records and gets a first header
+	fresh := info.Size() == 0
+	length := int(info.Size())
+	if fresh {
+		length = mmapData + cap*mq.size
+		if err := file.Truncate(int64(length)); err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+	if length < mmapData {
+		file.Close()
+		return nil, fmt.Errorf("%w: %s is too short", ErrCorrupt, path)
+	}
+
+	mq.file = file
+	mq.data, err = mmap(file, length)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	if fresh {
+		mq.capacity = cap
+		mq.commit()
+		return &mq, nil
+	}
+
+	err = mq.load()
+	if err == nil && (mq.capacity != cap || mq.size != codec.Size()) {
+		err = fmt.Errorf("%s holds %d records of %d bytes, not %d of %d", path, mq.capacity, mq.size, cap, codec.Size())
+	}
+	if err != nil {
+		mq.Close()
+		return nil, err
+	}
+	return &mq, nil
+}
+
+// SyncMmapQueue is a MmapQueue wrapped in a SynchronizedQueue
+type SyncMmapQueue[T any] struct {
+	*SynchronizedQueueImpl[T]
+	mq *MmapQueue[T] // the backend, for flushing and unmapping
+}
+
+// MmapIntQueue is the SyncMmapQueue for 'int'
+type MmapIntQueue = SyncMmapQueue[int]
+
+// Flush flushes the file to disk
+func (smq *SyncMmapQueue[T]) Flush() error {
+	// lock the mutex
+	smq.mtx.Lock()
+	defer smq.mtx.Unlock()
+
+	return smq.mq.Sync()
+}
+
+// Unmap closes the queue, then unmaps and closes the file.
+// the elements still in it stay in the file
+func (smq *SyncMmapQueue[T]) Unmap() error {
+	smq.Close()
+
+	// lock the mutex
+	smq.mtx.Lock()
+	defer smq.mtx.Unlock()
+
+	return smq.mq.Close()
+}
+
+// OpenSyncMmapOf opens a memory mapped queue and wraps it
+// in a SynchronizedQueue
+func OpenSyncMmapOf[T any](path string, cap int, codec RecordCodec[T]) (*SyncMmapQueue[T], error) {
+	var smq SyncMmapQueue[T]
+	var err error
+
+	// open the backend
+	smq.mq, err = OpenMmapQueueOf(path, cap, codec)
+	if err != nil {
+		return nil, err
+	}
+
+	// wrap it in the synchronized bounded queue
+	smq.SynchronizedQueueImpl = newSynchronizedQueueImpl[T](smq.mq)
+
+	return &smq, nil
+}
+
+// OpenMmapIntQueue opens a memory mapped queue of ints
+func OpenMmapIntQueue(path string, cap int) (*MmapIntQueue, error) {
+	return OpenSyncMmapOf[int](path, cap, IntRecord{})
+}
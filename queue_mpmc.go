@@ -0,0 +1,522 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// number of times a blocked Put or Get yields before it parks
+const mpmcSpins = 16
+
+// the top bit of tail is set by Close so a producer can't claim
+// a slot after the queue is closed
+const mpmcClosed uint64 = 1 << 63
+
+// mpmcPinned is added to the sequence number of a full slot while
+// a Peek reads it, so no Get takes the element from under it
+const mpmcPinned uint64 = 1 << 62
+
+// mpmcSlot is one element of the ring. its sequence number says
+// whose turn it is: pos when it is free for the producer claiming pos,
+// pos+1 when it holds the element for the consumer claiming pos,
+// and pos+1+mpmcPinned while a Peek is reading that element
+type mpmcSlot[T any] struct {
+	seq   atomic.Uint64
+	value T
+}
+
+// MPMCQueue is a bounded queue for any number of producers and consumers.
+// it is Dmitry Vyukov's ring of sequence-numbered slots: a Put or Get
+// claims a position with a compare-and-swap and then waits on nothing
+// but the sequence number of that one slot, so producers and consumers
+// don't serialize on a single mutex. TryPut and TryGet never take a lock.
+// Put and Get spin for a while and then park on a condition variable.
+//
+// a Peek pins the slots it reads, and a Get that claims a pinned
+// slot waits for the Peek to let go before it takes the element
+type MPMCQueue[T any] struct {
+	_          cacheLinePad
+	head       atomic.Uint64 // next position to read
+	_          cacheLinePad
+	tail       atomic.Uint64 // next position to write, plus the closed bit
+	_          cacheLinePad
+	slots      []mpmcSlot[T] // data
+	mask       uint64        // capacity - 1, for wrapping the positions
+	putwaiters atomic.Int32  // number of Puts parked on putcv
+	getwaiters atomic.Int32  // number of Gets parked on getcv
+	mtx        sync.Mutex    // only used for parking
+	putcv      *sync.Cond    // a condition variable for parked Puts
+	getcv      *sync.Cond    // a condition variable for parked Gets
+}
+
+// canPut reports whether a Put would not block
+func (q *MPMCQueue[T]) canPut() bool {
+	pos := q.tail.Load()
+	if pos&mpmcClosed != 0 {
+		return true
+	}
+	return int64(q.slots[pos&q.mask].seq.Load()-pos) >= 0
+}
+
+// canGet reports whether a Get would not block
+func (q *MPMCQueue[T]) canGet() bool {
+	pos := q.head.Load()
+	if int64(q.slots[pos&q.mask].seq.Load()-(pos+1)) >= 0 {
+		return true
+	}
+	return q.tail.Load()&mpmcClosed != 0
+}
+
+// park blocks until ready reports true or the context is done.
+// the waiter count is raised before ready is checked and the other
+// side checks the count after it moves a slot, so one of them sees the other
+func (q *MPMCQueue[T]) park(ctx context.Context, waiters *atomic.Int32, cv *sync.Cond, ready func() bool) error {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	// only a context that can be done needs to wake us
+	if ctx.Done() != nil {
+		stop := context.AfterFunc(ctx, broadcaster(cv))
+		defer stop()
+	}
+
+	waiters.Add(1)
+	defer waiters.Add(-1)
+
+	for !ready() {
+		if err := ctx.Err(); err != nil {
+			// pass on a wakeup this waiter may have taken
+			cv.Signal()
+			return err
+		}
+		cv.Wait()
+	}
+	return nil
+}
+
+// unpark wakes one parked caller, if there is one
+func (q *MPMCQueue[T]) unpark(waiters *atomic.Int32, cv *sync.Cond) {
+	if waiters.Load() > 0 {
+		q.mtx.Lock()
+		cv.Signal()
+		q.mtx.Unlock()
+	}
+}
+
+// TryPut adds an element onto the tail queue
+// if the queue is full, an error is returned
+func (q *MPMCQueue[T]) TryPut(value T) error {
+	var slot *mpmcSlot[T]
+
+	pos := q.tail.Load()
+	for {
+		if pos&mpmcClosed != 0 {
+			return ErrClosed
+		}
+
+		slot = &q.slots[pos&q.mask]
+		seq := slot.seq.Load()
+		dif := int64(seq - pos)
+		if dif == 0 {
+			// the slot is free, claim it
+			if q.tail.CompareAndSwap(pos, pos+1) {
+				break
+			}
+		} else if dif < 0 {
+			// the slot still holds the element from one lap ago
+			return ErrFull
+		}
+		// another producer got there first, or a Peek has the slot
+		if seq&mpmcPinned != 0 {
+			runtime.Gosched()
+		}
+		pos = q.tail.Load()
+	}
+
+	// the element is published by the store to seq
+	slot.value = value
+	slot.seq.Store(pos + 1)
+
+	q.unpark(&q.getwaiters, q.getcv)
+
+	return nil
+}
+
+// Put adds an element onto the tail queue
+// if the queue is full the function blocks
+func (q *MPMCQueue[T]) Put(value T) error {
+	// a background context is never done
+	return q.PutContext(context.Background(), value)
+}
+
+// PutContext adds an element to the tail of the queue
+// if the queue is full the function blocks until there is room
+// or the context is done
+func (q *MPMCQueue[T]) PutContext(ctx context.Context, value T) error {
+	for i := 0; ; i++ {
+		err := q.TryPut(value)
+		if !errors.Is(err, ErrFull) {
+			return err
+		}
+
+		if i < mpmcSpins {
+			runtime.Gosched()
+			continue
+		}
+
+		if err = q.park(ctx, &q.putwaiters, q.putcv, q.canPut); err != nil {
+			return err
+		}
+	}
+}
+
+// PutTimeout adds an element onto the tail queue
+// if the queue is full the function blocks for at most d
+// and returns ErrTimeout if there is still no room
+func (q *MPMCQueue[T]) PutTimeout(value T, d time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+
+	return timeoutError(q.PutContext(ctx, value))
+}
+
+// TryGet gets a value or returns an error if the queue is empty
+func (q *MPMCQueue[T]) TryGet() (T, error) {
+	var zero T
+	var slot *mpmcSlot[T]
+
+	pos := q.head.Load()
+	for {
+		slot = &q.slots[pos&q.mask]
+		seq := slot.seq.Load()
+		dif := int64(seq - (pos + 1))
+		if dif == 0 {
+			// the slot is full, claim it
+			if q.head.CompareAndSwap(pos, pos+1) {
+				break
+			}
+		} else if dif < 0 {
+			// nothing has been published here yet.
+			// once closed nothing more will be, unless it was claimed before Close
+			tail := q.tail.Load()
+			if tail&mpmcClosed != 0 && tail&^mpmcClosed == pos {
+				return zero, ErrClosed
+			}
+			return zero, ErrEmpty
+		}
+		// another consumer got there first, or a Peek has the slot
+		if seq&mpmcPinned != 0 {
+			runtime.Gosched()
+		}
+		pos = q.head.Load()
+	}
+
+	// a Peek may be reading the slot, wait for it to let go
+	for slot.seq.Load() != pos+1 {
+		runtime.Gosched()
+	}
+
+	// the slot is handed to the producer one lap ahead by the store to seq
+	value := slot.value
+	slot.value = zero
+	slot.seq.Store(pos + q.mask + 1)
+
+	q.unpark(&q.putwaiters, q.putcv)
+
+	return value, nil
+}
+
+// Get returns an element from the head of the queue
+// if the queue is empty,the caller blocks
+// once the queue is closed and drained ErrClosed is returned
+func (q *MPMCQueue[T]) Get() (T, error) {
+	// a background context is never done
+	return q.GetContext(context.Background())
+}
+
+// GetContext returns an element from the head of the queue
+// if the queue is empty the caller blocks until there is an element
+// or the context is done
+func (q *MPMCQueue[T]) GetContext(ctx context.Context) (T, error) {
+	for i := 0; ; i++ {
+		value, err := q.TryGet()
+		if !errors.Is(err, ErrEmpty) {
+			return value, err
+		}
+
+		if i < mpmcSpins {
+			runtime.Gosched()
+			continue
+		}
+
+		if err = q.park(ctx, &q.getwaiters, q.getcv, q.canGet); err != nil {
+			return value, err
+		}
+	}
+}
+
+// GetTimeout returns an element from the head of the queue
+// if the queue is empty the function blocks for at most d
+// and returns ErrTimeout if there is still no element
+func (q *MPMCQueue[T]) GetTimeout(d time.Duration) (T, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+
+	value, err := q.GetContext(ctx)
+	return value, timeoutError(err)
+}
+
+// PutMany adds as many of values as there is room for onto the tail queue
+// each element claims its own slot, so they go in one at a time.
+// ErrFull is returned if not all of them fit
+func (q *MPMCQueue[T]) PutMany(values []T) (int, error) {
+	for i, value := range values {
+		if err := q.TryPut(value); err != nil {
+			return i, err
+		}
+	}
+	return len(values), nil
+}
+
+// PutManyContext adds values onto the tail queue
+// if the queue is full the function blocks until there is room for
+// at least one of them or the context is done
+func (q *MPMCQueue[T]) PutManyContext(ctx context.Context, values []T) (int, error) {
+	if len(values) == 0 {
+		return 0, nil
+	}
+
+	// block for the first one only
+	if err := q.PutContext(ctx, values[0]); err != nil {
+		return 0, err
+	}
+
+	n, err := q.PutMany(values[1:])
+	if errors.Is(err, ErrFull) {
+		err = nil
+	}
+	return n + 1, err
+}
+
+// GetMany copies up to max elements from the head of the queue into dst
+// ErrEmpty is returned if there were none
+func (q *MPMCQueue[T]) GetMany(dst []T, max int) (int, error) {
+	var err error
+
+	_, max = batchLimits(len(dst), 0, max)
+	if max == 0 {
+		return 0, nil
+	}
+
+	n := 0
+	for n < max {
+		dst[n], err = q.TryGet()
+		if err != nil {
+			break
+		}
+		n++
+	}
+
+	if n > 0 {
+		return n, nil
+	}
+	return 0, err
+}
+
+// GetManyContext copies up to max elements from the head of the queue
+// into dst. the function blocks until min elements have been received
+// or the context is done, in which case the elements already received
+// are returned along with the error
+func (q *MPMCQueue[T]) GetManyContext(ctx context.Context, dst []T, min, max int) (int, error) {
+	var err error
+
+	min, max = batchLimits(len(dst), min, max)
+
+	n := 0
+	for n < min {
+		dst[n], err = q.GetContext(ctx)
+		if err != nil {
+			// a closed queue returns whatever was left
+			if errors.Is(err, ErrClosed) && n > 0 {
+				err = nil
+			}
+			return n, err
+		}
+		n++
+	}
+
+	m, _ := q.GetMany(dst[n:], max-n)
+	return n + m, nil
+}
+
+// pin marks up to n full slots from pos on as being read by a Peek.
+// it returns how many it pinned, stopping at the first one that is
+// not full. a slot pinned by another Peek is waited for
+func (q *MPMCQueue[T]) pin(pos uint64, n int) int {
+	for i := 0; i < n; i++ {
+		slot := &q.slots[(pos+uint64(i))&q.mask]
+		seq := pos + uint64(i) + 1
+		for !slot.seq.CompareAndSwap(seq, seq+mpmcPinned) {
+			if slot.seq.Load() != seq+mpmcPinned {
+				return i
+			}
+			runtime.Gosched()
+		}
+	}
+	return n
+}
+
+// unpin lets go of n slots pinned from pos on
+func (q *MPMCQueue[T]) unpin(pos uint64, n int) {
+	for i := 0; i < n; i++ {
+		seq := pos + uint64(i) + 1
+		q.slots[(pos+uint64(i))&q.mask].seq.CompareAndSwap(seq+mpmcPinned, seq)
+	}
+}
+
+// peek copies up to n elements from the head without taking them.
+// the slots are pinned while they are read, and it starts over
+// if a Get claimed the head before they were
+func (q *MPMCQueue[T]) peek(n int) ([]T, error) {
+	n = min(n, len(q.slots))
+	if n <= 0 {
+		return []T{}, nil
+	}
+
+	for {
+		pos := q.head.Load()
+		pinned := q.pin(pos, n)
+		if q.head.Load() != pos {
+			q.unpin(pos, pinned)
+			continue
+		}
+
+		values := make([]T, pinned)
+		for i := range values {
+			values[i] = q.slots[(pos+uint64(i))&q.mask].value
+		}
+		q.unpin(pos, pinned)
+
+		if pinned == 0 {
+			// same as TryGet, closed once nothing more can be published
+			tail := q.tail.Load()
+			if tail&mpmcClosed != 0 && tail&^mpmcClosed == pos {
+				return values, ErrClosed
+			}
+			return values, ErrEmpty
+		}
+		return values, nil
+	}
+}
+
+// Peek returns the element at the head of the queue without removing it
+// if the queue is empty the function blocks
+// once the queue is closed and drained ErrClosed is returned
+func (q *MPMCQueue[T]) Peek() (T, error) {
+	for i := 0; ; i++ {
+		value, err := q.TryPeek()
+		if !errors.Is(err, ErrEmpty) {
+			return value, err
+		}
+
+		if i < mpmcSpins {
+			runtime.Gosched()
+			continue
+		}
+
+		if err = q.park(context.Background(), &q.getwaiters, q.getcv, q.canGet); err != nil {
+			return value, err
+		}
+		// the element stays, pass the wakeup on to a Get
+		q.unpark(&q.getwaiters, q.getcv)
+	}
+}
+
+// TryPeek returns the element at the head of the queue without removing it
+// if the queue is empty, an error is returned
+func (q *MPMCQueue[T]) TryPeek() (T, error) {
+	var zero T
+
+	values, err := q.peek(1)
+	if err != nil {
+		return zero, err
+	}
+	return values[0], nil
+}
+
+// PeekN returns up to n elements from the head of the queue
+// without removing them
+func (q *MPMCQueue[T]) PeekN(n int) []T {
+	values, _ := q.peek(n)
+	return values
+}
+
+// Len is the current number of elements in the queue
+// elements that are still being put or got are counted
+func (q *MPMCQueue[T]) Len() int {
+	// head first, so tail can't be behind it
+	head := q.head.Load()
+	tail := q.tail.Load() &^ mpmcClosed
+
+	return min(int(tail-head), len(q.slots))
+}
+
+// Cap is the maximum number of elements the queue can hold
+func (q *MPMCQueue[T]) Cap() int {
+	return len(q.slots)
+}
+
+// Close stops the queue from accepting more elements
+// and wakes up every blocked Put and Get.
+// elements already in the queue can still be drained by Get
+func (q *MPMCQueue[T]) Close() {
+	for {
+		pos := q.tail.Load()
+		if pos&mpmcClosed != 0 || q.tail.CompareAndSwap(pos, pos|mpmcClosed) {
+			break
+		}
+	}
+
+	q.mtx.Lock()
+	q.putcv.Broadcast()
+	q.getcv.Broadcast()
+	q.mtx.Unlock()
+}
+
+// String
+func (q *MPMCQueue[T]) String() string {
+	return fmt.Sprintf("MPMCQueue Len:%v Cap:%v", q.Len(), q.Cap())
+}
+
+// NewMPMCQueueOf is a factory for creating bounded lock-free queues
+// of elements of type T. the capacity is rounded up to a power of two
+// It returns an instance of SynchronizedQueue
+func NewMPMCQueueOf[T any](size int) SynchronizedQueue[T] {
+	var q MPMCQueue[T]
+
+	capacity := 1
+	for capacity < size {
+		capacity <<= 1
+	}
+
+	q.slots = make([]mpmcSlot[T], capacity)
+	for i := range q.slots {
+		q.slots[i].seq.Store(uint64(i))
+	}
+	q.mask = uint64(capacity - 1)
+	q.putcv = sync.NewCond(&q.mtx)
+	q.getcv = sync.NewCond(&q.mtx)
+
+	return &q
+}
+
+// NewMPMCQueue is a factory for creating bounded lock-free queues
+// It returns an instance of SynchronizedQueue
+func NewMPMCQueue(size int) SynchronizedQueue[interface{}] {
+	return NewMPMCQueueOf[interface{}](size)
+}
@@ -1,100 +1,182 @@
 package queue
 
 import (
-	"errors"
+	"context"
 	"fmt"
 	"sync"
+	"time"
 )
 
-// NativeIntQueue iis a type specific implementation
-type NativeIntQueue struct {
-	queue []int			// data
-	head     int		// items are pulled from the head
-	tail     int		// items are pushed to the tail
-	length   int		// current number of elements in the queue
-	capacity int	    // maximum allowed elements total
-	mtx sync.Mutex      // a mutex for mutual exclusion
-	putcv *sync.Cond    // a condition variable for controlling Puts
-	getcv *sync.Cond    // a condition variable for controlling Gets
+// NativeQueue is a type specific implementation
+// elements are stored unboxed in a circular buffer of T
+type NativeQueue[T any] struct {
+	queue     []T        // data
+	head      int        // items are pulled from the head
+	tail      int        // items are pushed to the tail
+	length    int        // current number of elements in the queue
+	capacity  int        // maximum allowed elements total
+	mtx       sync.Mutex // a mutex for mutual exclusion
+	putcv     *sync.Cond // a condition variable for controlling Puts
+	getcv     *sync.Cond // a condition variable for controlling Gets
+	closed    bool       // no more Puts are accepted once set
+	bcastgets int        // number of waiters on getcv that need a Broadcast
 }
 
+// NativeIntQueue is the NativeQueue for 'int'
+type NativeIntQueue = NativeQueue[int]
+
 // TryPut adds an element onto the tail queue
 // if the queue is full, an error is returned
-func (nvq *NativeIntQueue) TryPut(value int) error {
+func (nvq *NativeQueue[T]) TryPut(value T) error {
 	// lock the mutex
-	nvq.putcv.L.Lock();
+	nvq.putcv.L.Lock()
 	defer nvq.putcv.L.Unlock()
 
+	// is queue closed ?
+	if nvq.closed {
+		return ErrClosed
+	}
+
 	// is queue full ?
 	if nvq.length == nvq.capacity {
 		// return an error
-		e := errors.New("queue is full")
-		return e;
+		return ErrFull
 	}
 
 	// queue had room, add it at the tail
 	nvq.queue[nvq.tail] = value
-	nvq.tail = (nvq.tail+1) % nvq.capacity
+	nvq.tail = (nvq.tail + 1) % nvq.capacity
 	nvq.length++
 
 	// signal a Get to wake up
-	nvq.getcv.Signal()
+	nvq.wakeGets(1)
 
 	// no error
 	return nil
-} 
+}
 
 // Put adds an element onto the tail queue
 // if the queue is full the function blocks
-func (nvq *NativeIntQueue) Put(value int)  {
+func (nvq *NativeQueue[T]) Put(value T) error {
+	// a background context is never done
+	return nvq.PutContext(context.Background(), value)
+}
+
+// PutContext adds an element onto the tail queue
+// if the queue is full the function blocks until there is room
+// or the context is done
+func (nvq *NativeQueue[T]) PutContext(ctx context.Context, value T) error {
 	// lock the mutex
 	nvq.putcv.L.Lock()
 	defer nvq.putcv.L.Unlock()
 
-	// block until a value is in the queue
-	for nvq.length == nvq.capacity {
+	// wake the waiters if the context is done while blocked
+	if nvq.length == nvq.capacity && ctx.Done() != nil {
+		stop := context.AfterFunc(ctx, broadcaster(nvq.putcv))
+		defer stop()
+	}
+
+	// block until there is room in the queue
+	for nvq.length == nvq.capacity && !nvq.closed {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		// release and wait
 		nvq.putcv.Wait()
 	}
-	
+
+	// Close wakes up all blocked Puts
+	if nvq.closed {
+		return ErrClosed
+	}
+
 	// queue has room, add it at the tail
 	nvq.queue[nvq.tail] = value
-	nvq.tail = (nvq.tail+1) % nvq.capacity
+	nvq.tail = (nvq.tail + 1) % nvq.capacity
 	nvq.length++
 
 	// signal a Get to wake up
-	nvq.getcv.Signal()
-} 
+	nvq.wakeGets(1)
 
+	return nil
+}
+
+// PutTimeout adds an element onto the tail queue
+// if the queue is full the function blocks for at most d
+// and returns ErrTimeout if there is still no room
+func (nvq *NativeQueue[T]) PutTimeout(value T, d time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+
+	return timeoutError(nvq.PutContext(ctx, value))
+}
 
 // Get returns an element from the head of the queue
 // if the queue is empty,the caller blocks
-func (nvq *NativeIntQueue) Get() int {
+// once the queue is closed and drained ErrClosed is returned
+func (nvq *NativeQueue[T]) Get() (T, error) {
+	// a background context is never done
+	return nvq.GetContext(context.Background())
+}
+
+// GetContext returns an element from the head of the queue
+// if the queue is empty,the caller blocks until there is an element
+// or the context is done
+func (nvq *NativeQueue[T]) GetContext(ctx context.Context) (T, error) {
+	var zero T
+
 	// lock the mutex
 	nvq.getcv.L.Lock()
 	defer nvq.getcv.L.Unlock()
 
+	// wake the waiters if the context is done while blocked
+	if nvq.length == 0 && ctx.Done() != nil {
+		stop := context.AfterFunc(ctx, broadcaster(nvq.getcv))
+		defer stop()
+	}
+
 	// block until a value is in the queue
-	for nvq.length == 0 {
+	for nvq.length == 0 && !nvq.closed {
+		if err := ctx.Err(); err != nil {
+			return zero, err
+		}
 		// release and wait
 		nvq.getcv.Wait()
 	}
 
+	// closed and nothing left to drain
+	if nvq.length == 0 {
+		return zero, ErrClosed
+	}
+
 	// at this point there is at least one item in the queue
 	// remove the head
 	value := nvq.queue[nvq.head]
-	nvq.head = (nvq.head + 1)  % nvq.capacity
+	nvq.queue[nvq.head] = zero
+	nvq.head = (nvq.head + 1) % nvq.capacity
 	nvq.length--
 
 	// signal a Put to wake up
 	nvq.putcv.Signal()
 
-	return value
+	return value, nil
+}
+
+// GetTimeout returns an element from the head of the queue
+// if the queue is empty the function blocks for at most d
+// and returns ErrTimeout if there is still no element
+func (nvq *NativeQueue[T]) GetTimeout(d time.Duration) (T, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+
+	value, err := nvq.GetContext(ctx)
+	return value, timeoutError(err)
 }
 
 // TryGet gets a value or returns an error if the queue is empty
-func (nvq *NativeIntQueue) TryGet() (int, error) {
-	var value int
+func (nvq *NativeQueue[T]) TryGet() (T, error) {
+	var zero T
+	var value T
 	var err error
 
 	// lock the mutex
@@ -104,55 +186,318 @@ func (nvq *NativeIntQueue) TryGet() (int, error) {
 	// is the queue empty?
 	if nvq.length > 0 {
 		value = nvq.queue[nvq.head]
-		nvq.head = (nvq.head + 1)  % nvq.capacity
+		nvq.queue[nvq.head] = zero
+		nvq.head = (nvq.head + 1) % nvq.capacity
 		nvq.length--
+	} else if nvq.closed {
+		err = ErrClosed
 	} else {
-		value = 0
-		err = errors.New("queue is empty");
+		err = ErrEmpty
 	}
-	
+
 	// signal a Put to wake up
 	nvq.putcv.Signal()
 
 	return value, err
-	
+
+}
+
+// PutMany adds as many of values as there is room for onto the tail queue
+// under a single lock hold. ErrFull is returned if not all of them fit
+func (nvq *NativeQueue[T]) PutMany(values []T) (int, error) {
+	// lock the mutex
+	nvq.putcv.L.Lock()
+	defer nvq.putcv.L.Unlock()
+
+	// is queue closed ?
+	if nvq.closed {
+		return 0, ErrClosed
+	}
+
+	n := nvq.pushMany(values)
+	if n < len(values) {
+		return n, ErrFull
+	}
+	return n, nil
+}
+
+// restore puts all of values on the tail queue or none of them
+// if there isn't room. the times of a snapshot are dropped
+func (nvq *NativeQueue[T]) restore(values []T, times []time.Time, kind snapshotTimes) (int, error) {
+	// lock the mutex
+	nvq.putcv.L.Lock()
+	defer nvq.putcv.L.Unlock()
+
+	// is queue closed ?
+	if nvq.closed {
+		return 0, ErrClosed
+	}
+
+	// is there room for all of them ?
+	if len(values) > nvq.capacity-nvq.length {
+		return 0, ErrFull
+	}
+
+	return nvq.pushMany(values), nil
+}
+
+// PutManyContext adds values onto the tail queue under a single lock hold
+// if the queue is full the function blocks until there is room for
+// at least one of them or the context is done
+func (nvq *NativeQueue[T]) PutManyContext(ctx context.Context, values []T) (int, error) {
+	if len(values) == 0 {
+		return 0, nil
+	}
+
+	// lock the mutex
+	nvq.putcv.L.Lock()
+	defer nvq.putcv.L.Unlock()
+
+	// wake the waiters if the context is done while blocked
+	if nvq.length == nvq.capacity && ctx.Done() != nil {
+		stop := context.AfterFunc(ctx, broadcaster(nvq.putcv))
+		defer stop()
+	}
+
+	// block until there is room in the queue
+	for nvq.length == nvq.capacity && !nvq.closed {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		// release and wait
+		nvq.putcv.Wait()
+	}
+
+	// Close wakes up all blocked Puts
+	if nvq.closed {
+		return 0, ErrClosed
+	}
+
+	return nvq.pushMany(values), nil
+}
+
+// GetMany copies up to max elements from the head of the queue into dst
+// under a single lock hold. ErrEmpty is returned if there were none
+func (nvq *NativeQueue[T]) GetMany(dst []T, max int) (int, error) {
+	_, max = batchLimits(len(dst), 0, max)
+
+	// lock the mutex
+	nvq.getcv.L.Lock()
+	defer nvq.getcv.L.Unlock()
+
+	n := nvq.popMany(dst[:max])
+	if n == 0 && max > 0 {
+		if nvq.closed {
+			return 0, ErrClosed
+		}
+		return 0, ErrEmpty
+	}
+	return n, nil
 }
 
-// Len is the current number of elements in the queue 
-func (nvq *NativeIntQueue) Len() int {
+// GetManyContext copies up to max elements from the head of the queue
+// into dst under a single lock hold. if fewer than min elements are
+// in the queue the function blocks until there are or the context is done.
+// a closed queue returns whatever is left
+func (nvq *NativeQueue[T]) GetManyContext(ctx context.Context, dst []T, min, max int) (int, error) {
+	min, max = batchLimits(len(dst), min, max)
+	if max == 0 {
+		return 0, nil
+	}
+
+	// lock the mutex
+	nvq.getcv.L.Lock()
+	defer nvq.getcv.L.Unlock()
+
+	// wake the waiters if the context is done while blocked
+	if nvq.length < min && ctx.Done() != nil {
+		stop := context.AfterFunc(ctx, broadcaster(nvq.getcv))
+		defer stop()
+	}
+
+	// Puts broadcast instead of signal while someone waits for a batch
+	if min > 1 {
+		nvq.bcastgets++
+		defer func() { nvq.bcastgets-- }()
+	}
+
+	// block until enough values are in the queue
+	for nvq.length < min && !nvq.closed {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		// release and wait
+		nvq.getcv.Wait()
+	}
+
+	// closed and nothing left to drain
+	if nvq.length == 0 {
+		return 0, ErrClosed
+	}
+
+	return nvq.popMany(dst[:max]), nil
+}
+
+// Peek returns the element at the head of the queue without removing it
+// if the queue is empty the caller blocks
+// once the queue is closed and drained ErrClosed is returned
+func (nvq *NativeQueue[T]) Peek() (T, error) {
+	var zero T
+
+	// lock the mutex
+	nvq.getcv.L.Lock()
+	defer nvq.getcv.L.Unlock()
+
+	// a Peek doesn't consume the element it is woken for,
+	// so Puts broadcast to make sure a Get is woken too
+	nvq.bcastgets++
+	defer func() { nvq.bcastgets-- }()
+
+	// block until a value is in the queue
+	for nvq.length == 0 && !nvq.closed {
+		// release and wait
+		nvq.getcv.Wait()
+	}
+
+	// closed and nothing left to drain
+	if nvq.length == 0 {
+		return zero, ErrClosed
+	}
+
+	return nvq.queue[nvq.head], nil
+}
+
+// TryPeek returns the element at the head of the queue without removing it
+// if the queue is empty returns an error
+func (nvq *NativeQueue[T]) TryPeek() (T, error) {
+	var zero T
+
+	// lock the mutex
+	nvq.getcv.L.Lock()
+	defer nvq.getcv.L.Unlock()
+
+	if nvq.length == 0 {
+		if nvq.closed {
+			return zero, ErrClosed
+		}
+		return zero, ErrEmpty
+	}
+
+	return nvq.queue[nvq.head], nil
+}
+
+// PeekN returns up to n elements from the head of the queue
+// without removing them
+func (nvq *NativeQueue[T]) PeekN(n int) []T {
+	// lock the mutex
+	nvq.getcv.L.Lock()
+	defer nvq.getcv.L.Unlock()
+
+	n = max(0, min(n, nvq.length))
+	values := make([]T, n)
+	c := copy(values, nvq.queue[nvq.head:])
+	copy(values[c:], nvq.queue)
+	return values
+}
+
+// pushMany copies as many of values as fit into the circular buffer
+// and wakes up the blocked Gets. the caller must hold the mutex
+func (nvq *NativeQueue[T]) pushMany(values []T) int {
+	n := min(len(values), nvq.capacity-nvq.length)
+	if n <= 0 {
+		return 0
+	}
+
+	// copy up to the end of the buffer, then wrap to the start
+	c := copy(nvq.queue[nvq.tail:], values[:n])
+	copy(nvq.queue, values[c:n])
+	nvq.tail = (nvq.tail + n) % nvq.capacity
+	nvq.length += n
+
+	nvq.wakeGets(n)
+
+	return n
+}
+
+// popMany copies up to len(dst) elements out of the circular buffer
+// and wakes up the blocked Puts. the caller must hold the mutex
+func (nvq *NativeQueue[T]) popMany(dst []T) int {
+	n := min(len(dst), nvq.length)
+	if n <= 0 {
+		return 0
+	}
+
+	// copy up to the end of the buffer, then wrap to the start
+	c := copy(dst[:n], nvq.queue[nvq.head:])
+	copy(dst[c:n], nvq.queue)
+	clear(nvq.queue[nvq.head : nvq.head+c])
+	clear(nvq.queue[:n-c])
+	nvq.head = (nvq.head + n) % nvq.capacity
+	nvq.length -= n
+
+	wake(nvq.putcv, n)
+
+	return n
+}
+
+// wakeGets wakes the Gets after n elements were added
+func (nvq *NativeQueue[T]) wakeGets(n int) {
+	if nvq.bcastgets > 0 {
+		nvq.getcv.Broadcast()
+		return
+	}
+	wake(nvq.getcv, n)
+}
+
+// Len is the current number of elements in the queue
+func (nvq *NativeQueue[T]) Len() int {
 	return nvq.length
 }
 
 // Cap is the maximum number of elements the queue can hold
-func (nvq *NativeIntQueue) Cap() int {
+func (nvq *NativeQueue[T]) Cap() int {
 	return cap(nvq.queue)
 }
 
-// Close is for cleanup
-func (nvq *NativeIntQueue) Close() {
-	// noop
+// Close stops the queue from accepting more elements
+// and wakes up every blocked Put and Get.
+// elements already in the queue can still be drained by Get
+func (nvq *NativeQueue[T]) Close() {
+	nvq.mtx.Lock()
+	defer nvq.mtx.Unlock()
+
+	nvq.closed = true
+	nvq.putcv.Broadcast()
+	nvq.getcv.Broadcast()
 }
 
 // String
-func (nvq *NativeIntQueue) String() string {
-	return fmt.Sprintf("NativeIntQueue Len:%v Cap:%v",nvq.Len(),nvq.Cap())
+func (nvq *NativeQueue[T]) String() string {
+	return fmt.Sprintf("NativeQueue Len:%v Cap:%v", nvq.Len(), nvq.Cap())
 }
 
-// NewNativeQueue is a factory for creating queues
+// NewNativeQueueOf is a factory for creating queues
 // that use a condition variable and circular buffer
-// for the specific type. In this case 'int'. 
-func NewNativeQueue(size int) *NativeIntQueue {
-	var nvq NativeIntQueue
-	
+// for the specific type T. The elements are never boxed.
+func NewNativeQueueOf[T any](size int) *NativeQueue[T] {
+	var nvq NativeQueue[T]
+
 	// allocate the whole slice during init
-	nvq.queue = make([]int,size,size)
+	nvq.queue = make([]T, size, size)
 	nvq.head = 0
 	nvq.tail = 0
 	nvq.length = 0
 	nvq.capacity = size
-	nvq.mtx = sync.Mutex{} 
+	nvq.mtx = sync.Mutex{}
 	nvq.putcv = sync.NewCond(&nvq.mtx)
 	nvq.getcv = sync.NewCond(&nvq.mtx)
 
 	return &nvq
 }
+
+// NewNativeQueue is a factory for creating queues
+// that use a condition variable and circular buffer
+// for the specific type. In this case 'int'.
+func NewNativeQueue(size int) *NativeIntQueue {
+	return NewNativeQueueOf[int](size)
+}
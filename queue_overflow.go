@@ -0,0 +1,127 @@
+package queue
+
+import (
+	"errors"
+)
+
+// OverflowPolicy says what a Put does when the queue is full
+type OverflowPolicy int
+
+const (
+	// OverflowBlock makes Put wait for room and TryPut return ErrFull.
+	// the default
+	OverflowBlock OverflowPolicy = iota
+	// OverflowReject makes Put and TryPut return ErrFull right away
+	OverflowReject
+	// OverflowDropOldest evicts the element at the head to make room.
+	// on a priority queue the head is the most urgent element, not the
+	// oldest, so use OverflowDropNewest or OverflowReject there
+	OverflowDropOldest
+	// OverflowDropNewest discards the element being put
+	OverflowDropNewest
+
+	// overflowBackend leaves a full queue to a backend
+	// that applies its own policy in Push
+	overflowBackend OverflowPolicy = -1
+)
+
+// overflowQueue is implemented by the backends that can
+// have an overflow policy of their own
+type overflowQueue interface {
+	// the policy applied by Push
+	overflowPolicy() OverflowPolicy
+
+	// number of elements the policy dropped
+	Dropped() int
+}
+
+// overflow applies the policy to a value put on a full queue.
+// the caller must hold the mutex
+func (sq *SynchronizedQueueImpl[T]) overflow(value T, push func(T) error) error {
+	switch sq.policy {
+	case overflowBackend:
+		// the backend evicts, discards or rejects
+		if err := push(value); err != nil {
+			return err
+		}
+
+		// signal a Get to wake up
+		sq.wakeGets(1)
+		return nil
+
+	case OverflowDropOldest:
+		// a backend that drops elements may have emptied out instead
+		oldest, perr := sq.queue.Pop()
+		if perr != nil && !errors.Is(perr, ErrEmpty) {
+			return perr
+		}
+		if err := push(value); err != nil {
+			return err
+		}
+		if perr == nil {
+			sq.drop(oldest)
+		}
+
+		// signal a Get to wake up
+		sq.wakeGets(1)
+		return nil
+
+	case OverflowDropNewest:
+		sq.drop(value)
+		return nil
+	}
+	return ErrFull
+}
+
+// overflowMany applies the policy to the values that didn't fit
+// after n others went in. the caller must hold the mutex
+func (sq *SynchronizedQueueImpl[T]) overflowMany(n int, values []T) (int, error) {
+	for _, value := range values {
+		if err := sq.overflow(value, sq.queue.Push); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// drop counts an element lost to the policy and hands it to evict
+func (sq *SynchronizedQueueImpl[T]) drop(value T) {
+	sq.dropped++
+	if sq.evict != nil {
+		sq.evict(value)
+	}
+}
+
+// Dropped is the number of elements evicted or discarded
+// by the overflow policy
+func (sq *SynchronizedQueueImpl[T]) Dropped() int {
+	// lock the mutex
+	sq.mtx.Lock()
+	defer sq.mtx.Unlock()
+
+	if sq.policy == overflowBackend {
+		return sq.queue.(overflowQueue).Dropped()
+	}
+	return sq.dropped
+}
+
+// NewSynchronizedQueueOverflowOf is a factory for creating bounded queues
+// of elements of type T that handle a full queue with the given policy.
+// evict, if not nil, is called with each element the policy drops.
+// it runs under the lock of the queue so it must not call back into it.
+// the policy replaces any the backend applies itself
+func NewSynchronizedQueueOverflowOf[T any](q Queue[T], policy OverflowPolicy, evict func(value T)) *SynchronizedQueueImpl[T] {
+	sq := newSynchronizedQueueImpl(q)
+
+	sq.policy = policy
+	sq.evict = evict
+
+	return sq
+}
+
+// NewSyncCircularOverflowOf wraps a typed circular buffer in a
+// SynchronizedQueue that handles a full queue with the given policy
+func NewSyncCircularOverflowOf[T any](cap int, policy OverflowPolicy, evict func(value T)) *SynchronizedQueueImpl[T] {
+	return NewSynchronizedQueueOverflowOf(NewCircularQueueOf[T](cap), policy, evict)
+}
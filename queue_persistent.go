@@ -0,0 +1,194 @@
+package queue
+
+import (
+	"fmt"
+	"time"
+)
+
+// defaults for PersistentOptions
+const (
+	persistentSegmentSize   = 4 << 20
+	persistentFsyncInterval = time.Second
+)
+
+// PersistentOptions configures a PersistentQueue
+type PersistentOptions[T any] struct {
+	// turns elements into log records and back, GobCodec when nil
+	Codec Codec[T]
+
+	// when the log is flushed to disk, FsyncAlways by default
+	Fsync FsyncPolicy
+
+	// how often FsyncInterval flushes, a second when 0
+	FsyncInterval time.Duration
+
+	// a new log segment is started past this many bytes, 4MB when 0
+	SegmentSize int64
+}
+
+// PersistentQueue is a Queue that survives a crash. every Push and Pop
+// is appended to a write-ahead log of segment files in a directory
+// before it takes effect, and the log is replayed when the queue is
+// opened again. segments whose elements have all been popped are
+// deleted. the elements are also kept in memory, so Pop and Peek
+// never read the disk.
+//
+// wrap it with NewSynchronizedQueueOf to share it between goroutines,
+// and Close it once the wrapper is done with it
+type PersistentQueue[T any] struct {
+	queue    *GrowableQueue[T] // the elements, in memory
+	log      *walLog           // the elements, on disk
+	codec    Codec[T]
+	capacity int    // maximum number of elements the queue can hold
+	head     uint64 // seq of the element at the head
+	next     uint64 // seq of the next element pushed
+}
+
+func (pq *PersistentQueue[T]) Len() int {
+	return pq.queue.Len()
+}
+
+func (pq *PersistentQueue[T]) Cap() int {
+	return pq.capacity
+}
+
+// Push logs the element, then adds it
+func (pq *PersistentQueue[T]) Push(value T) error {
+	if pq.queue.Len() >= pq.capacity {
+		return ErrFull
+	}
+
+	data, err := pq.codec.Encode(value)
+	if err != nil {
+		return err
+	}
+	if err := pq.log.append(walPush, pq.next, data, pq.next); err != nil {
+		return err
+	}
+
+	pq.queue.Push(value)
+	pq.next++
+
+	return nil
+}
+
+// Pop logs the removal, then removes the element at the head
+func (pq *PersistentQueue[T]) Pop() (T, error) {
+	if pq.queue.Len() == 0 {
+		var zero T
+		return zero, ErrEmpty
+	}
+
+	if err := pq.log.append(walPop, pq.head, nil, pq.next); err != nil {
+		var zero T
+		return zero, err
+	}
+
+	value, _ := pq.queue.Pop()
+	pq.head++
+
+	// the segments behind the head can go. one that can't be
+	// deleted now is tried again on the next Pop
+	pq.log.compact(pq.head)
+
+	return value, nil
+}
+
+func (pq *PersistentQueue[T]) Peek() (T, error) {
+	return pq.queue.Peek()
+}
+
+// PeekN returns up to n elements from the head without removing them
+func (pq *PersistentQueue[T]) PeekN(n int) []T {
+	return pq.queue.PeekN(n)
+}
+
+// Sync flushes the log to disk, whatever the fsync policy
+func (pq *PersistentQueue[T]) Sync() error {
+	return pq.log.sync()
+}
+
+// Close flushes and closes the log. the queue can't be used after that
+func (pq *PersistentQueue[T]) Close() error {
+	return pq.log.close()
+}
+
+// String
+func (pq *PersistentQueue[T]) String() string {
+	return fmt.Sprintf("PersistentQueue Len:%v Cap:%v", pq.Len(), pq.Cap())
+}
+
+// replay rebuilds the queue from the records read back from the log
+func (pq *PersistentQueue[T]) replay(pushes []walRecord, popped uint64) error {
+	// everything before the oldest segment has been popped too
+	pq.head = max(pq.log.first(), popped)
+	pq.next = pq.head
+
+	for i, record := range pushes {
+		if i > 0 && record.seq != pushes[i-1].seq+1 {
+			return fmt.Errorf("%w: push %d follows %d", ErrCorrupt, record.seq, pushes[i-1].seq)
+		}
+		pq.next = max(pq.next, record.seq+1)
+		if record.seq < pq.head {
+			continue
+		}
+
+		value, err := pq.codec.Decode(record.value)
+		if err != nil {
+			return err
+		}
+		pq.queue.Push(value)
+	}
+	return nil
+}
+
+// OpenPersistentQueueOf opens the persistent queue of elements of type T
+// kept in directory dir, creating it if it doesn't exist
+func OpenPersistentQueueOf[T any](dir string, cap int, options PersistentOptions[T]) (*PersistentQueue[T], error) {
+	var pq PersistentQueue[T]
+	var pushes []walRecord
+	var popped uint64
+
+	if options.Codec == nil {
+		options.Codec = GobCodec[T]{}
+	}
+	if options.FsyncInterval <= 0 {
+		options.FsyncInterval = persistentFsyncInterval
+	}
+	if options.SegmentSize <= 0 {
+		options.SegmentSize = persistentSegmentSize
+	}
+
+	pq.capacity = cap
+	pq.codec = options.Codec
+	pq.queue = NewGrowableQueueOf[T](Unbounded).(*GrowableQueue[T])
+
+	// read the log back, the pushes and how far the pops got
+	log, err := openWAL(dir, options.SegmentSize, options.Fsync, options.FsyncInterval, func(record walRecord) error {
+		switch record.kind {
+		case walPush:
+			pushes = append(pushes, record)
+		case walPop:
+			popped = max(popped, record.seq+1)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	pq.log = log
+
+	if err := pq.replay(pushes, popped); err != nil {
+		log.close()
+		return nil, err
+	}
+
+	return &pq, nil
+}
+
+// OpenPersistentQueue opens a persistent queue of interface{} elements.
+// with the default GobCodec the concrete types must be registered
+// with gob.Register
+func OpenPersistentQueue(dir string, cap int, options PersistentOptions[interface{}]) (*PersistentQueue[interface{}], error) {
+	return OpenPersistentQueueOf[interface{}](dir, cap, options)
+}
@@ -2,86 +2,312 @@ package queue
 
 import (
 	"container/heap"
-	"errors"
+	"context"
+	"encoding/json"
 	"fmt"
+	"time"
 )
 
 // PriorityItem - for a type agnostic priority queue
 // modeled after the PriorityQueue Example at
 // https://golang.org/pkg/container/heap/#example__priorityQueue
-type PriorityItem struct {
-	value interface{}
+type PriorityItem[T any] struct {
+	value    T
 	priority int
+	seq      uint64          // insertion order, breaks ties between equal priorities
+	handle   *PriorityHandle // tracks the item's place in the heap, if it was asked for
+	since    time.Time       // when the item was put, only set for aging
 }
 
-type PrioritySlice []PriorityItem
+// PriorityHandle refers to an item in a PriorityQueue
+// so it can be reprioritized or removed while it is queued
+type PriorityHandle struct {
+	index int // position in the heap, -1 once the item has left it
+}
+
+// NewPriorityItem creates an item to put on a priority queue.
+// items with a lower priority are got first
+func NewPriorityItem[T any](value T, priority int) PriorityItem[T] {
+	return PriorityItem[T]{value: value, priority: priority}
+}
+
+// Value is the value carried by the item
+func (item PriorityItem[T]) Value() T {
+	return item.value
+}
+
+// Priority is the priority of the item
+func (item PriorityItem[T]) Priority() int {
+	return item.priority
+}
 
-func (h PrioritySlice) Len() int           { return len(h) }
-func (h PrioritySlice) Less(i, j int) bool { return h[i].priority < h[j].priority }
-func (h PrioritySlice) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+// priorityWire is how a PriorityItem is encoded by gob and JSON
+type priorityWire[T any] struct {
+	Value    T
+	Priority int
+}
+
+// MarshalJSON encodes the value and the priority of the item
+func (item PriorityItem[T]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(priorityWire[T]{item.value, item.priority})
+}
+
+// UnmarshalJSON decodes an item encoded by MarshalJSON
+func (item *PriorityItem[T]) UnmarshalJSON(data []byte) error {
+	var wire priorityWire[T]
+	if err := json.Unmarshal(data, &wire); err != nil {
+		return err
+	}
+	*item = NewPriorityItem(wire.Value, wire.Priority)
+	return nil
+}
 
-func (h *PrioritySlice) Push(x interface{}) {
+// GobEncode encodes the value and the priority of the item
+func (item PriorityItem[T]) GobEncode() ([]byte, error) {
+	return GobCodec[priorityWire[T]]{}.Encode(priorityWire[T]{item.value, item.priority})
+}
+
+// GobDecode decodes an item encoded by GobEncode
+func (item *PriorityItem[T]) GobDecode(data []byte) error {
+	wire, err := GobCodec[priorityWire[T]]{}.Decode(data)
+	if err != nil {
+		return err
+	}
+	*item = NewPriorityItem(wire.Value, wire.Priority)
+	return nil
+}
+
+// PriorityOrder says which end of the priorities is got first
+type PriorityOrder int
+
+const (
+	// MinFirst gets the lowest priority first, a min-heap. the default
+	MinFirst PriorityOrder = iota
+	// MaxFirst gets the highest priority first, a max-heap
+	MaxFirst
+)
+
+// PrioritySlice orders items by ascending priority.
+// items with equal priorities stay in the order they were put
+type PrioritySlice[T any] []PriorityItem[T]
+
+func (h PrioritySlice[T]) Len() int           { return len(h) }
+func (h PrioritySlice[T]) Less(i, j int) bool { return h[i].before(h[j], MinFirst) }
+func (h PrioritySlice[T]) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *PrioritySlice[T]) Push(x interface{}) {
 	// Push and Pop use pointer receivers because they modify the slice's length,
 	// not just its contents.
-	*h = append(*h, x.(PriorityItem))
+	*h = append(*h, x.(PriorityItem[T]))
 }
 
-func (h *PrioritySlice) Pop() interface{} {
+func (h *PrioritySlice[T]) Pop() interface{} {
 	old := *h
 	n := len(old)
 	x := old[n-1]
+	// release the reference held by the slot
+	old[n-1] = PriorityItem[T]{}
 	*h = old[0 : n-1]
 	return x
 }
 
+// before reports whether item is got before other in the given order.
+// equal priorities are first come first served
+func (item PriorityItem[T]) before(other PriorityItem[T], order PriorityOrder) bool {
+	if item.priority != other.priority {
+		if order == MaxFirst {
+			return item.priority > other.priority
+		}
+		return item.priority < other.priority
+	}
+	return item.seq < other.seq
+}
+
+// priorityHeap is the PrioritySlice with the order chosen for the queue
+type priorityHeap[T any] struct {
+	PrioritySlice[T]
+	order PriorityOrder
+	aging *PriorityAging // nil unless the priorities improve with time
+	now   time.Time      // the time the aged priorities are compared at
+}
+
+func (h *priorityHeap[T]) Less(i, j int) bool {
+	a, b := h.PrioritySlice[i], h.PrioritySlice[j]
+	if h.aging != nil {
+		a.priority = h.aging.effective(a.priority, h.now.Sub(a.since), h.order)
+		b.priority = h.aging.effective(b.priority, h.now.Sub(b.since), h.order)
+	}
+	return a.before(b, h.order)
+}
+
+// Swap keeps the handles pointing at their items
+func (h *priorityHeap[T]) Swap(i, j int) {
+	s := h.PrioritySlice
+	s[i], s[j] = s[j], s[i]
+	if s[i].handle != nil {
+		s[i].handle.index = i
+	}
+	if s[j].handle != nil {
+		s[j].handle.index = j
+	}
+}
+
 // PriorityQueue - a Queue backed by a container/heap - PriorityQueue example
-type PriorityQueue struct {
-	heap PrioritySlice	 // use the priority queue example in priority_queue.go
-	capacity int	     // maximum number of elements the queue can hold
+type PriorityQueue[T any] struct {
+	heap     priorityHeap[T] // use the priority queue example in priority_queue.go
+	capacity int             // maximum number of elements the queue can hold
+	seq      uint64          // insertion order of the next item
 }
 
-func (pq PriorityQueue) Len() int {
+func (pq *PriorityQueue[T]) Len() int {
 	return pq.heap.Len()
 }
 
-func (pq PriorityQueue) Cap() int {
+func (pq *PriorityQueue[T]) Cap() int {
 	return pq.capacity
 }
 
-func (pq *PriorityQueue) Push(value interface{}) error {
+func (pq *PriorityQueue[T]) Push(value PriorityItem[T]) error {
+	// an item that was got and put again is a new item
+	value.handle = nil
+
+	return pq.push(value)
+}
+
+// PushPriority adds a value with the given priority and returns
+// a handle for UpdatePriority and Remove
+func (pq *PriorityQueue[T]) PushPriority(value T, priority int) (*PriorityHandle, error) {
+	item := NewPriorityItem(value, priority)
+	item.handle = &PriorityHandle{index: pq.heap.Len()}
+
+	if err := pq.push(item); err != nil {
+		return nil, err
+	}
+	return item.handle, nil
+}
+
+// push stamps the insertion order and inserts
+func (pq *PriorityQueue[T]) push(value PriorityItem[T]) error {
 	if pq.heap.Len() >= pq.capacity {
-		return errors.New("queue is full")
+		return ErrFull
+	}
+	value.seq = pq.seq
+	pq.seq++
+	if pq.heap.aging != nil {
+		value.since = pq.heap.aging.now()
 	}
-	// insert 
 	heap.Push(&pq.heap, value)
 
 	return nil
 }
 
-func (pq *PriorityQueue) Pop() (interface{}, error) {
+func (pq *PriorityQueue[T]) Pop() (PriorityItem[T], error) {
 	if pq.heap.Len() == 0 {
-		return nil, errors.New("queue is empty)")
+		return PriorityItem[T]{}, ErrEmpty
 	}
 
-	value := heap.Pop(&pq.heap)
+	pq.rebalance()
+	value := heap.Pop(&pq.heap).(PriorityItem[T])
 
-	return value,nil
+	return pq.release(value), nil
+}
+
+// UpdatePriority changes the priority of a queued item in O(log n)
+// ErrNotQueued is returned if it has already left the queue
+func (pq *PriorityQueue[T]) UpdatePriority(h *PriorityHandle, priority int) error {
+	if !pq.holds(h) {
+		return ErrNotQueued
+	}
+	pq.heap.PrioritySlice[h.index].priority = priority
+	heap.Fix(&pq.heap, h.index)
+
+	return nil
+}
+
+// Remove takes a queued item out of the queue in O(log n)
+// ErrNotQueued is returned if it has already left the queue
+func (pq *PriorityQueue[T]) Remove(h *PriorityHandle) (PriorityItem[T], error) {
+	if !pq.holds(h) {
+		return PriorityItem[T]{}, ErrNotQueued
+	}
+
+	value := heap.Remove(&pq.heap, h.index).(PriorityItem[T])
+
+	return pq.release(value), nil
+}
+
+// holds reports whether h refers to an item in this queue
+func (pq *PriorityQueue[T]) holds(h *PriorityHandle) bool {
+	return h != nil && h.index >= 0 && h.index < pq.heap.Len() &&
+		pq.heap.PrioritySlice[h.index].handle == h
+}
+
+// release marks the handle of an item that left the heap
+func (pq *PriorityQueue[T]) release(value PriorityItem[T]) PriorityItem[T] {
+	if value.handle != nil {
+		value.handle.index = -1
+		value.handle = nil
+	}
+	return value
+}
+
+// Peek returns the item that is got first without removing it
+func (pq *PriorityQueue[T]) Peek() (PriorityItem[T], error) {
+	if pq.heap.Len() == 0 {
+		return PriorityItem[T]{}, ErrEmpty
+	}
+
+	// the root of the heap is the next item
+	pq.rebalance()
+	return pq.heap.PrioritySlice[0], nil
+}
+
+// PeekN returns up to n items in priority order without removing them
+func (pq *PriorityQueue[T]) PeekN(n int) []PriorityItem[T] {
+	n = max(0, min(n, pq.heap.Len()))
+	values := make([]PriorityItem[T], n)
+
+	// pop from a copy of the heap
+	// without the handles, which must keep pointing into the queue
+	pq.rebalance()
+	h := pq.heap
+	h.PrioritySlice = make(PrioritySlice[T], pq.heap.Len())
+	copy(h.PrioritySlice, pq.heap.PrioritySlice)
+	for i := range h.PrioritySlice {
+		h.PrioritySlice[i].handle = nil
+	}
+	for i := 0; i < n; i++ {
+		values[i] = heap.Pop(&h).(PriorityItem[T])
+	}
+	return values
 }
 
 // String
-func (pq *PriorityQueue) String() string {
-	return fmt.Sprintf("PriorityQueue Len:%v Cap:%v",pq.Len(),pq.Cap())
+func (pq *PriorityQueue[T]) String() string {
+	return fmt.Sprintf("PriorityQueue Len:%v Cap:%v", pq.Len(), pq.Cap())
 }
 
-// create a new heap queue
-func NewPriorityQueue(cap int) Queue {
-	var pq PriorityQueue
+// create a new heap queue with values of type T
+// the lowest priority is got first
+func NewPriorityQueueOf[T any](cap int) Queue[PriorityItem[T]] {
+	return NewPriorityQueueOrderOf[T](cap, MinFirst)
+}
+
+// create a new heap queue with values of type T
+// that gets them in the given order
+func NewPriorityQueueOrderOf[T any](cap int, order PriorityOrder) Queue[PriorityItem[T]] {
+	return newPriorityQueue[T](cap, order, nil)
+}
+
+// newPriorityQueue does the work of the PriorityQueue factories
+func newPriorityQueue[T any](cap int, order PriorityOrder, aging *PriorityAging) *PriorityQueue[T] {
+	var pq PriorityQueue[T]
 
 	// set the capacity
 	pq.capacity = cap
 
 	// set up an empty heap to start with
-	pq.heap = make(PrioritySlice,0)
+	pq.heap = priorityHeap[T]{PrioritySlice: make(PrioritySlice[T], 0), order: order, aging: aging}
 
 	// initialize it
 	heap.Init(&pq.heap)
@@ -89,16 +315,117 @@ func NewPriorityQueue(cap int) Queue {
 	return &pq
 }
 
-// wrap the heap queue in a Synchronized queue
-func NewSyncPriority(cap int) SynchronizedQueue {
-	var pq Queue
-	var bq SynchronizedQueue 
+// SyncPriorityQueue is a PriorityQueue wrapped in a SynchronizedQueue.
+// besides the SynchronizedQueue of PriorityItem it takes and returns
+// the value and priority separately, so the caller never has to
+// build or unpack a PriorityItem
+type SyncPriorityQueue[T any] struct {
+	*SynchronizedQueueImpl[PriorityItem[T]]
+	pq *PriorityQueue[T] // the backend, for the handle operations
+}
+
+// PutPriority adds a value with the given priority and returns
+// a handle for UpdatePriority and Remove
+// if the queue is full the function blocks
+func (spq *SyncPriorityQueue[T]) PutPriority(value T, priority int) (*PriorityHandle, error) {
+	var h *PriorityHandle
 
-	// create the int heap
-	pq = NewPriorityQueue(cap)
+	err := spq.put(context.Background(), NewPriorityItem(value, priority), func(item PriorityItem[T]) (err error) {
+		h, err = spq.pq.PushPriority(item.value, item.priority)
+		return err
+	})
+	return h, err
+}
+
+// TryPutPriority adds a value with the given priority and returns
+// a handle for UpdatePriority and Remove
+// if the queue is full, an error is returned
+func (spq *SyncPriorityQueue[T]) TryPutPriority(value T, priority int) (*PriorityHandle, error) {
+	var h *PriorityHandle
+
+	err := spq.tryPut(NewPriorityItem(value, priority), func(item PriorityItem[T]) (err error) {
+		h, err = spq.pq.PushPriority(item.value, item.priority)
+		return err
+	})
+	return h, err
+}
+
+// UpdatePriority changes the priority of a queued value
+// ErrNotQueued is returned if it has already been got or removed
+func (spq *SyncPriorityQueue[T]) UpdatePriority(h *PriorityHandle, priority int) error {
+	// lock the mutex
+	spq.mtx.Lock()
+	defer spq.mtx.Unlock()
+
+	return spq.pq.UpdatePriority(h, priority)
+}
+
+// Remove takes a queued value out of the queue and returns it
+// ErrNotQueued is returned if it has already been got or removed
+func (spq *SyncPriorityQueue[T]) Remove(h *PriorityHandle) (T, error) {
+	// lock the mutex
+	spq.mtx.Lock()
+	defer spq.mtx.Unlock()
+
+	item, err := spq.pq.Remove(h)
+	if err != nil {
+		return item.value, err
+	}
+
+	// signal a Put to wake up
+	spq.putcv.Signal()
+
+	return item.value, nil
+}
+
+// GetPriority returns the next value and its priority
+// if the queue is empty the caller blocks
+// once the queue is closed and drained ErrClosed is returned
+func (spq *SyncPriorityQueue[T]) GetPriority() (T, int, error) {
+	item, err := spq.Get()
+	return item.value, item.priority, err
+}
+
+// TryGetPriority returns the next value and its priority
+// if the queue is empty an error is returned
+func (spq *SyncPriorityQueue[T]) TryGetPriority() (T, int, error) {
+	item, err := spq.TryGet()
+	return item.value, item.priority, err
+}
+
+// wrap the typed heap queue in a Synchronized queue
+// the lowest priority is got first
+func NewSyncPriorityOf[T any](cap int) *SyncPriorityQueue[T] {
+	return NewSyncPriorityOrderOf[T](cap, MinFirst)
+}
+
+// wrap the typed heap queue in a Synchronized queue
+// that gets them in the given order
+func NewSyncPriorityOrderOf[T any](cap int, order PriorityOrder) *SyncPriorityQueue[T] {
+	return newSyncPriority(newPriorityQueue[T](cap, order, nil))
+}
+
+// newSyncPriority does the work of the SyncPriorityQueue factories
+func newSyncPriority[T any](pq *PriorityQueue[T]) *SyncPriorityQueue[T] {
+	var spq SyncPriorityQueue[T]
+
+	// attach the heap
+	spq.pq = pq
 
 	// wrap it in the syncrhonized bounded queue
-	bq = NewSynchronizedQueue(pq) 
+	spq.SynchronizedQueueImpl = newSynchronizedQueueImpl[PriorityItem[T]](spq.pq)
 
-	return bq
+	return &spq
+}
+
+// create a new heap queue
+// elements pushed onto it must be PriorityItem[interface{}],
+// made with NewPriorityItem. anything else returns ErrType
+func NewPriorityQueue(cap int) Queue[interface{}] {
+	return &anyQueue[PriorityItem[interface{}]]{queue: NewPriorityQueueOf[interface{}](cap)}
+}
+
+// wrap the heap queue in a Synchronized queue
+func NewSyncPriority(cap int) SynchronizedQueue[interface{}] {
+	return NewSynchronizedQueueOf(NewPriorityQueue(cap))
 }
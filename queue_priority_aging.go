@@ -0,0 +1,82 @@
+package queue
+
+import (
+	"container/heap"
+	"time"
+)
+
+// AgingFunc says how many levels the priority of an item improves
+// after it has waited in the queue for the given time.
+// it should never decrease as the wait gets longer
+type AgingFunc func(waited time.Duration) int
+
+// AgeEvery returns an AgingFunc that improves the priority
+// by one level for every d an item waits.
+// a d that isn't positive turns aging off
+func AgeEvery(d time.Duration) AgingFunc {
+	if d <= 0 {
+		return func(waited time.Duration) int { return 0 }
+	}
+	return func(waited time.Duration) int {
+		return int(waited / d)
+	}
+}
+
+// PriorityAging turns on aging for a PriorityQueue: the longer an item
+// waits the better its effective priority gets, so a steady stream
+// of more urgent items can't starve it forever. an AgingFunc that
+// keeps growing, e.g. AgeEvery, guarantees every item is got eventually;
+// a step function that levels off only bounds the boost.
+//
+// the heap is rebuilt, in O(n), by the first Get or Peek after the
+// clock moves. with time.Now that is every one of them. a clock that
+// only ticks once per aging step, e.g. time.Now().Truncate(time.Second)
+// for AgeEvery(time.Second), rebuilds it once per step instead
+type PriorityAging struct {
+	Age AgingFunc        // how much the priority improves with the wait
+	Now func() time.Time // the clock, time.Now when nil
+}
+
+// now reads the clock
+func (a *PriorityAging) now() time.Time {
+	if a.Now == nil {
+		return time.Now()
+	}
+	return a.Now()
+}
+
+// effective is the priority an item is ordered by after waiting
+func (a *PriorityAging) effective(priority int, waited time.Duration, order PriorityOrder) int {
+	age := a.Age(max(0, waited))
+	if order == MaxFirst {
+		return priority + age
+	}
+	return priority - age
+}
+
+// rebalance reorders the heap by the aged priorities at the current
+// time. the heap is only valid for one point in time, so this is
+// done in O(n) before each read whenever the clock has moved
+func (pq *PriorityQueue[T]) rebalance() {
+	if pq.heap.aging == nil {
+		return
+	}
+
+	now := pq.heap.aging.now()
+	if now.Equal(pq.heap.now) {
+		return
+	}
+	pq.heap.now = now
+	heap.Init(&pq.heap)
+}
+
+// create a new heap queue with values of type T
+// that gets them in the given order, with priorities that age
+func NewPriorityQueueAgingOf[T any](cap int, order PriorityOrder, aging PriorityAging) Queue[PriorityItem[T]] {
+	return newPriorityQueue[T](cap, order, &aging)
+}
+
+// wrap the typed heap queue with aging in a Synchronized queue
+func NewSyncPriorityAgingOf[T any](cap int, order PriorityOrder, aging PriorityAging) *SyncPriorityQueue[T] {
+	return newSyncPriority(newPriorityQueue[T](cap, order, &aging))
+}
@@ -0,0 +1,149 @@
+package queue
+
+import (
+	"container/heap"
+	"fmt"
+)
+
+// funcItem is one element of a PriorityFuncQueue
+type funcItem[T any] struct {
+	value T
+	seq   uint64 // insertion order, breaks ties between equal elements
+}
+
+// funcHeap is a container/heap ordered by a caller supplied less function
+type funcHeap[T any] struct {
+	items []funcItem[T]
+	less  func(a, b T) bool
+}
+
+func (h *funcHeap[T]) Len() int      { return len(h.items) }
+func (h *funcHeap[T]) Swap(i, j int) { h.items[i], h.items[j] = h.items[j], h.items[i] }
+
+// Less orders by the less function.
+// elements it considers equal stay in the order they were put
+func (h *funcHeap[T]) Less(i, j int) bool {
+	a, b := h.items[i], h.items[j]
+	if h.less(a.value, b.value) {
+		return true
+	}
+	if h.less(b.value, a.value) {
+		return false
+	}
+	return a.seq < b.seq
+}
+
+func (h *funcHeap[T]) Push(x interface{}) {
+	h.items = append(h.items, x.(funcItem[T]))
+}
+
+func (h *funcHeap[T]) Pop() interface{} {
+	old := h.items
+	n := len(old)
+	x := old[n-1]
+	// release the reference held by the slot
+	old[n-1] = funcItem[T]{}
+	h.items = old[0 : n-1]
+	return x
+}
+
+// PriorityFuncQueue - a Queue backed by a container/heap that orders
+// its elements with a less function instead of an int priority,
+// e.g. by a deadline, by a composite key or descending
+type PriorityFuncQueue[T any] struct {
+	heap     funcHeap[T] // elements in heap order
+	capacity int         // maximum number of elements the queue can hold
+	seq      uint64      // insertion order of the next element
+}
+
+func (pq *PriorityFuncQueue[T]) Len() int {
+	return pq.heap.Len()
+}
+
+func (pq *PriorityFuncQueue[T]) Cap() int {
+	return pq.capacity
+}
+
+func (pq *PriorityFuncQueue[T]) Push(value T) error {
+	if pq.heap.Len() >= pq.capacity {
+		return ErrFull
+	}
+	// stamp the insertion order and insert
+	heap.Push(&pq.heap, funcItem[T]{value, pq.seq})
+	pq.seq++
+
+	return nil
+}
+
+func (pq *PriorityFuncQueue[T]) Pop() (T, error) {
+	if pq.heap.Len() == 0 {
+		var zero T
+		return zero, ErrEmpty
+	}
+
+	item := heap.Pop(&pq.heap).(funcItem[T])
+
+	return item.value, nil
+}
+
+// Peek returns the least element without removing it
+func (pq *PriorityFuncQueue[T]) Peek() (T, error) {
+	if pq.heap.Len() == 0 {
+		var zero T
+		return zero, ErrEmpty
+	}
+
+	// the root of the heap is the least element
+	return pq.heap.items[0].value, nil
+}
+
+// PeekN returns up to n elements in order without removing them
+func (pq *PriorityFuncQueue[T]) PeekN(n int) []T {
+	n = max(0, min(n, pq.heap.Len()))
+	values := make([]T, n)
+
+	// pop from a copy of the heap
+	h := funcHeap[T]{make([]funcItem[T], pq.heap.Len()), pq.heap.less}
+	copy(h.items, pq.heap.items)
+	for i := 0; i < n; i++ {
+		values[i] = heap.Pop(&h).(funcItem[T]).value
+	}
+	return values
+}
+
+// String
+func (pq *PriorityFuncQueue[T]) String() string {
+	return fmt.Sprintf("PriorityFuncQueue Len:%v Cap:%v", pq.Len(), pq.Cap())
+}
+
+// NewPriorityQueueFunc creates a heap queue of elements of type T.
+// less(a, b) reports whether a is got before b
+func NewPriorityQueueFunc[T any](cap int, less func(a, b T) bool) Queue[T] {
+	var pq PriorityFuncQueue[T]
+
+	// set the capacity
+	pq.capacity = cap
+
+	// set up an empty heap to start with
+	pq.heap = funcHeap[T]{make([]funcItem[T], 0), less}
+
+	// initialize it
+	heap.Init(&pq.heap)
+
+	return &pq
+}
+
+// NewSyncPriorityFunc wraps a heap queue ordered by less
+// in a Synchronized queue
+func NewSyncPriorityFunc[T any](cap int, less func(a, b T) bool) SynchronizedQueue[T] {
+	var pq Queue[T]
+	var bq SynchronizedQueue[T]
+
+	// create the heap
+	pq = NewPriorityQueueFunc(cap, less)
+
+	// wrap it in the synchronized bounded queue
+	bq = NewSynchronizedQueueOf(pq)
+
+	return bq
+}
@@ -0,0 +1,370 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrUnknownTag is returned by Ack and Nack for a delivery that is
+// no longer in flight: it was already acked or nacked, or its
+// visibility timeout ran out and the item went back on the queue
+var ErrUnknownTag = errors.New("delivery is not in flight")
+
+// DeliveryTag identifies one delivery of an item by a ReliableQueue
+type DeliveryTag uint64
+
+// Delivery is an item handed out by a ReliableQueue
+type Delivery[T any] struct {
+	value    T
+	tag      DeliveryTag
+	attempts int
+	err      error
+}
+
+// Value is the item
+func (d Delivery[T]) Value() T {
+	return d.value
+}
+
+// Tag identifies this delivery to Ack and Nack
+func (d Delivery[T]) Tag() DeliveryTag {
+	return d.tag
+}
+
+// Attempts is the number of times the item has been delivered,
+// this one included
+func (d Delivery[T]) Attempts() int {
+	return d.attempts
+}
+
+// LastError is why the previous delivery failed, nil on the first.
+// ErrTimeout if its visibility timeout ran out
+func (d Delivery[T]) LastError() error {
+	return d.err
+}
+
+// reliableItem is an item that has been delivered before
+type reliableItem[T any] struct {
+	value    T
+	attempts int   // deliveries so far
+	err      error // why the last one failed
+}
+
+// inflightItem is an item that is delivered and not yet acked
+type inflightItem[T any] struct {
+	reliableItem[T]
+	timer *time.Timer // returns the item when the visibility timeout runs out
+}
+
+// ReliableQueue hands out the items of a SynchronizedQueue backend
+// without losing them when a consumer dies. Receive makes an item
+// invisible for the visibility timeout instead of removing it.
+// Ack removes it for good, while Nack or the timeout running out
+// returns it to the head of the queue to be delivered again.
+//
+// items that come back are kept by the ReliableQueue, not the backend,
+// and don't count against its bound. one that keeps failing can be
+// moved to a dead-letter queue, see NewReliableQueueDeadLetterOf
+type ReliableQueue[T any] struct {
+	queue       SynchronizedQueue[T]             // the items not yet delivered
+	visibility  time.Duration                    // how long a delivery stays invisible
+	mtx         sync.Mutex                       // a mutex for mutual exclusion
+	returned    []reliableItem[T]                // items that came back, the head is last
+	inflight    map[DeliveryTag]*inflightItem[T] // items delivered and not yet acked
+	tag         DeliveryTag                      // tag of the last delivery
+	wake        context.Context                  // done when an item comes back
+	kickWake    context.CancelFunc               // wakes the Receives waiting on wake
+	maxAttempts int                              // deliveries before an item is dead
+	dead        SynchronizedQueue[DeadLetter[T]] // where dead items go, nil to keep them
+}
+
+// Put adds an item onto the tail of the backend
+// if the queue is full the function blocks
+func (rq *ReliableQueue[T]) Put(value T) error {
+	return rq.queue.Put(value)
+}
+
+// PutContext adds an item onto the tail of the backend
+// if the queue is full the function blocks until there is room
+// or the context is done
+func (rq *ReliableQueue[T]) PutContext(ctx context.Context, value T) error {
+	return rq.queue.PutContext(ctx, value)
+}
+
+// TryPut adds an item onto the tail of the backend
+// if the queue is full, an error is returned
+func (rq *ReliableQueue[T]) TryPut(value T) error {
+	return rq.queue.TryPut(value)
+}
+
+// Receive delivers the item at the head of the queue
+// if the queue is empty the function blocks
+// once the queue is closed and drained, with nothing in flight
+// that could come back, ErrClosed is returned
+func (rq *ReliableQueue[T]) Receive() (Delivery[T], error) {
+	return rq.ReceiveContext(context.Background())
+}
+
+// ReceiveContext delivers the item at the head of the queue
+// if the queue is empty the function blocks until there is an item
+// or the context is done
+func (rq *ReliableQueue[T]) ReceiveContext(ctx context.Context) (Delivery[T], error) {
+	var zero Delivery[T]
+
+	for {
+		// lock the mutex
+		rq.mtx.Lock()
+		if d, ok := rq.redeliver(); ok {
+			rq.mtx.Unlock()
+			return d, nil
+		}
+		wake := rq.wake
+		rq.mtx.Unlock()
+
+		// wait on the backend, but give up when an item comes back
+		gctx, cancel := context.WithCancel(ctx)
+		stop := context.AfterFunc(wake, cancel)
+		value, err := rq.queue.GetContext(gctx)
+		stop()
+		cancel()
+
+		switch {
+		case err == nil:
+			return rq.deliver(reliableItem[T]{value: value}), nil
+
+		case ctx.Err() != nil:
+			return zero, ctx.Err()
+
+		case errors.Is(err, ErrClosed):
+			// drained, but what is in flight may still come back
+			if err := rq.linger(ctx); err != nil {
+				return zero, err
+			}
+
+		case wake.Err() == nil:
+			return zero, err
+		}
+	}
+}
+
+// ReceiveTimeout delivers the item at the head of the queue
+// if the queue is empty the function blocks for at most d
+func (rq *ReliableQueue[T]) ReceiveTimeout(d time.Duration) (Delivery[T], error) {
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+
+	delivery, err := rq.ReceiveContext(ctx)
+	return delivery, timeoutError(err)
+}
+
+// TryReceive delivers the item at the head of the queue
+// if the queue is empty, an error is returned
+func (rq *ReliableQueue[T]) TryReceive() (Delivery[T], error) {
+	var zero Delivery[T]
+
+	// lock the mutex
+	rq.mtx.Lock()
+	if d, ok := rq.redeliver(); ok {
+		rq.mtx.Unlock()
+		return d, nil
+	}
+	inflight := len(rq.inflight)
+	rq.mtx.Unlock()
+
+	value, err := rq.queue.TryGet()
+	if errors.Is(err, ErrClosed) && inflight > 0 {
+		// not over yet, something may come back
+		return zero, ErrEmpty
+	}
+	if err != nil {
+		return zero, err
+	}
+	return rq.deliver(reliableItem[T]{value: value}), nil
+}
+
+// Ack removes a delivered item for good
+func (rq *ReliableQueue[T]) Ack(tag DeliveryTag) error {
+	// lock the mutex
+	rq.mtx.Lock()
+	defer rq.mtx.Unlock()
+
+	item, ok := rq.inflight[tag]
+	if !ok {
+		return ErrUnknownTag
+	}
+	item.timer.Stop()
+	delete(rq.inflight, tag)
+
+	// the last one lets a Receive waiting on a closed queue go
+	if len(rq.inflight) == 0 {
+		rq.kick()
+	}
+	return nil
+}
+
+// Nack returns a delivered item to the head of the queue to be
+// delivered again. reason, which may be nil, is why it failed
+func (rq *ReliableQueue[T]) Nack(tag DeliveryTag, reason error) error {
+	// lock the mutex
+	rq.mtx.Lock()
+	defer rq.mtx.Unlock()
+
+	item, ok := rq.inflight[tag]
+	if !ok {
+		return ErrUnknownTag
+	}
+	item.timer.Stop()
+	delete(rq.inflight, tag)
+
+	rq.giveBack(item.reliableItem, reason)
+
+	return nil
+}
+
+// expire returns an item whose visibility timeout ran out
+func (rq *ReliableQueue[T]) expire(tag DeliveryTag) {
+	// lock the mutex
+	rq.mtx.Lock()
+	defer rq.mtx.Unlock()
+
+	// it may have been acked on the way
+	item, ok := rq.inflight[tag]
+	if !ok {
+		return
+	}
+	delete(rq.inflight, tag)
+
+	rq.giveBack(item.reliableItem, ErrTimeout)
+}
+
+// giveBack puts an item that failed back at the head,
+// or in the dead-letter queue if it has run out of attempts.
+// the caller must hold the mutex
+func (rq *ReliableQueue[T]) giveBack(item reliableItem[T], reason error) {
+	item.err = reason
+	if !rq.deadLetter(item) {
+		rq.returned = append(rq.returned, item)
+	}
+	rq.kick()
+}
+
+// redeliver delivers the item that came back last, if there is one.
+// the caller must hold the mutex
+func (rq *ReliableQueue[T]) redeliver() (Delivery[T], bool) {
+	n := len(rq.returned)
+	if n == 0 {
+		return Delivery[T]{}, false
+	}
+	item := rq.returned[n-1]
+	// release the reference held by the slot
+	rq.returned[n-1] = reliableItem[T]{}
+	rq.returned = rq.returned[:n-1]
+
+	return rq.track(item), true
+}
+
+// deliver hands out an item taken from the backend
+func (rq *ReliableQueue[T]) deliver(item reliableItem[T]) Delivery[T] {
+	// lock the mutex
+	rq.mtx.Lock()
+	defer rq.mtx.Unlock()
+
+	return rq.track(item)
+}
+
+// track counts a delivery and hides the item for the visibility timeout.
+// the caller must hold the mutex
+func (rq *ReliableQueue[T]) track(item reliableItem[T]) Delivery[T] {
+	rq.tag++
+	tag := rq.tag
+
+	item.attempts++
+	rq.inflight[tag] = &inflightItem[T]{
+		reliableItem: item,
+		timer:        time.AfterFunc(rq.visibility, func() { rq.expire(tag) }),
+	}
+
+	return Delivery[T]{value: item.value, tag: tag, attempts: item.attempts, err: item.err}
+}
+
+// linger waits on a closed and drained queue until an item comes back
+// or nothing is left in flight, in which case it returns ErrClosed
+func (rq *ReliableQueue[T]) linger(ctx context.Context) error {
+	// lock the mutex
+	rq.mtx.Lock()
+	if len(rq.returned) == 0 && len(rq.inflight) == 0 {
+		rq.mtx.Unlock()
+		return ErrClosed
+	}
+	wake := rq.wake
+	rq.mtx.Unlock()
+
+	select {
+	case <-wake.Done():
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// kick wakes the Receives waiting for an item to come back.
+// the caller must hold the mutex
+func (rq *ReliableQueue[T]) kick() {
+	rq.kickWake()
+	rq.wake, rq.kickWake = context.WithCancel(context.Background())
+}
+
+// Len is the number of items waiting to be delivered
+func (rq *ReliableQueue[T]) Len() int {
+	// lock the mutex
+	rq.mtx.Lock()
+	defer rq.mtx.Unlock()
+
+	return rq.queue.Len() + len(rq.returned)
+}
+
+// InFlight is the number of items delivered and not yet acked
+func (rq *ReliableQueue[T]) InFlight() int {
+	// lock the mutex
+	rq.mtx.Lock()
+	defer rq.mtx.Unlock()
+
+	return len(rq.inflight)
+}
+
+// Cap is the bound of the backend
+func (rq *ReliableQueue[T]) Cap() int {
+	return rq.queue.Cap()
+}
+
+// Close stops the queue from accepting more items. the items
+// in it, and the ones in flight that come back, are still delivered
+func (rq *ReliableQueue[T]) Close() {
+	rq.queue.Close()
+}
+
+// String
+func (rq *ReliableQueue[T]) String() string {
+	return fmt.Sprintf("ReliableQueue Len:%v Cap:%v InFlight:%v", rq.Len(), rq.Cap(), rq.InFlight())
+}
+
+// NewReliableQueueOf wraps the backend q so its items are delivered
+// until they are acked. a delivery that is neither acked nor nacked
+// within visibility goes back on the queue
+func NewReliableQueueOf[T any](q SynchronizedQueue[T], visibility time.Duration) *ReliableQueue[T] {
+	var rq ReliableQueue[T]
+
+	rq.queue = q
+	rq.visibility = visibility
+	rq.inflight = make(map[DeliveryTag]*inflightItem[T])
+	rq.wake, rq.kickWake = context.WithCancel(context.Background())
+
+	return &rq
+}
+
+func NewReliableQueue(q SynchronizedQueue[interface{}], visibility time.Duration) *ReliableQueue[interface{}] {
+	return NewReliableQueueOf[interface{}](q, visibility)
+}
@@ -2,30 +2,30 @@ package queue
 
 import (
 	"container/ring"
-	"errors"
 	"fmt"
 )
 
 // RingQueue - a Queue backed by a container/ring
-type RingQueue struct {
-	ring *ring.Ring	 // preallocated ring for all slots in the queue
-	head *ring.Ring  // head of the queue
-	tail *ring.Ring  // tail of the queue
-	capacity int     // maximum number of elements the ring can hold
-	length int       // current number of element in the ring
+// container/ring stores interface{} so elements are still boxed internally
+type RingQueue[T any] struct {
+	ring     *ring.Ring // preallocated ring for all slots in the queue
+	head     *ring.Ring // head of the queue
+	tail     *ring.Ring // tail of the queue
+	capacity int        // maximum number of elements the ring can hold
+	length   int        // current number of element in the ring
 }
 
-func (rq *RingQueue) Len() int {
+func (rq *RingQueue[T]) Len() int {
 	return rq.length
 }
 
-func (rq *RingQueue) Cap() int {
+func (rq *RingQueue[T]) Cap() int {
 	return rq.capacity
 }
 
-func (rq *RingQueue) Push(value interface{}) error {
+func (rq *RingQueue[T]) Push(value T) error {
 	if rq.length >= rq.capacity {
-		return errors.New("queue is full")
+		return ErrFull
 	}
 	// insert at tail
 	rq.tail.Value = value
@@ -39,13 +39,16 @@ func (rq *RingQueue) Push(value interface{}) error {
 	return nil
 }
 
-func (rq *RingQueue) Pop() (interface{}, error) {
+func (rq *RingQueue[T]) Pop() (T, error) {
 	if rq.length == 0 {
-		return nil, errors.New("queue is empty)")
+		var zero T
+		return zero, ErrEmpty
 	}
 
 	// get the value at the head
-	value := rq.head.Value
+	// a nil interface{} element yields the zero value
+	value, _ := rq.head.Value.(T)
+	rq.head.Value = nil
 
 	// increment the head
 	rq.head = rq.head.Next()
@@ -53,16 +56,41 @@ func (rq *RingQueue) Pop() (interface{}, error) {
 	// decrement length
 	rq.length--
 
-	return value,nil
+	return value, nil
+}
+
+func (rq *RingQueue[T]) Peek() (T, error) {
+	if rq.length == 0 {
+		var zero T
+		return zero, ErrEmpty
+	}
+
+	// a nil interface{} element yields the zero value
+	value, _ := rq.head.Value.(T)
+
+	return value, nil
+}
+
+// PeekN returns up to n elements from the head without removing them
+func (rq *RingQueue[T]) PeekN(n int) []T {
+	n = max(0, min(n, rq.length))
+	values := make([]T, n)
+	r := rq.head
+	for i := 0; i < n; i++ {
+		values[i], _ = r.Value.(T)
+		r = r.Next()
+	}
+	return values
 }
 
 // String
-func (rq *RingQueue) String() string {
-	return fmt.Sprintf("RingQueue Len:%v Cap:%v",rq.Len(),rq.Cap())
+func (rq *RingQueue[T]) String() string {
+	return fmt.Sprintf("RingQueue Len:%v Cap:%v", rq.Len(), rq.Cap())
 }
 
-func NewRingQueue(cap int) Queue {
-	var rq RingQueue
+// NewRingQueueOf creates a container/ring queue of elements of type T
+func NewRingQueueOf[T any](cap int) Queue[T] {
+	var rq RingQueue[T]
 	rq.capacity = cap
 	rq.length = 0
 	rq.ring = ring.New(cap)
@@ -72,13 +100,22 @@ func NewRingQueue(cap int) Queue {
 	return &rq
 }
 
-func NewSyncRing(cap int) SynchronizedQueue {
-	var rq Queue
-	var bq SynchronizedQueue 
+// NewSyncRingOf wraps a typed ring queue in a SynchronizedQueue
+func NewSyncRingOf[T any](cap int) SynchronizedQueue[T] {
+	var rq Queue[T]
+	var bq SynchronizedQueue[T]
 
-	rq = NewRingQueue(cap)
+	rq = NewRingQueueOf[T](cap)
 
-	bq = NewSynchronizedQueue(rq) 
+	bq = NewSynchronizedQueueOf(rq)
 
 	return bq
-}
\ No newline at end of file
+}
+
+func NewRingQueue(cap int) Queue[interface{}] {
+	return NewRingQueueOf[interface{}](cap)
+}
+
+func NewSyncRing(cap int) SynchronizedQueue[interface{}] {
+	return NewSyncRingOf[interface{}](cap)
+}
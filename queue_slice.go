@@ -1,7 +1,6 @@
 package queue
 
 import (
-	"errors"
 	"fmt"
 )
 
@@ -9,22 +8,22 @@ import (
 // this version appends and removes elements so the slice grows and shrinks
 // memory is not preallocated
 // see CircularQueue for a version that preallocates a slice of capacity
-type SliceQueue struct {
-	slice []interface{}
-	capacity int	     // maximum number of elements the queue can hold
+type SliceQueue[T any] struct {
+	slice    []T
+	capacity int // maximum number of elements the queue can hold
 }
 
-func (sq *SliceQueue) Len() int {
+func (sq *SliceQueue[T]) Len() int {
 	return len(sq.slice)
 }
 
-func (sq *SliceQueue) Cap() int {
+func (sq *SliceQueue[T]) Cap() int {
 	return sq.capacity
 }
 
-func (sq *SliceQueue) Push(value interface{}) error {
+func (sq *SliceQueue[T]) Push(value T) error {
 	if len(sq.slice) >= sq.capacity {
-		return errors.New("queue is full")
+		return ErrFull
 	}
 	// insert at end
 	sq.slice = append(sq.slice, value)
@@ -32,42 +31,71 @@ func (sq *SliceQueue) Push(value interface{}) error {
 	return nil
 }
 
-func (sq *SliceQueue) Pop() (interface{}, error) {
+func (sq *SliceQueue[T]) Pop() (T, error) {
+	var zero T
+
 	if len(sq.slice) == 0 {
-		return nil, errors.New("queue is empty)")
+		return zero, ErrEmpty
 	}
 
 	// get the value at the front
 	value := sq.slice[0]
 
-	// remove the front
+	// release the reference held by the slot and remove the front.
+	// append moves the elements to a new array once the old one is used up
+	// so the prefix that was popped is not kept forever
+	sq.slice[0] = zero
 	sq.slice = sq.slice[1:]
 
+	return value, nil
+}
 
-	return value,nil
+func (sq *SliceQueue[T]) Peek() (T, error) {
+	if len(sq.slice) == 0 {
+		var zero T
+		return zero, ErrEmpty
+	}
+	return sq.slice[0], nil
 }
 
-// String
-func (sq *SliceQueue)  String() string {
-	return fmt.Sprintf("SliceQueue Len:%v Cap:%v",sq.Len(),sq.Cap())
+// PeekN returns up to n elements from the head without removing them
+func (sq *SliceQueue[T]) PeekN(n int) []T {
+	n = max(0, min(n, len(sq.slice)))
+	values := make([]T, n)
+	copy(values, sq.slice)
+	return values
 }
 
+// String
+func (sq *SliceQueue[T]) String() string {
+	return fmt.Sprintf("SliceQueue Len:%v Cap:%v", sq.Len(), sq.Cap())
+}
 
-func NewSliceQueue(cap int) Queue {
-	var sq SliceQueue
+// NewSliceQueueOf creates a slice queue of elements of type T
+func NewSliceQueueOf[T any](cap int) Queue[T] {
+	var sq SliceQueue[T]
 	sq.capacity = cap
-	sq.slice = make([]interface{},0)
+	sq.slice = make([]T, 0)
 
 	return &sq
 }
 
-func NewSyncSlice(cap int) SynchronizedQueue {
-	var sq Queue
-	var bq SynchronizedQueue 
+// NewSyncSliceOf wraps a typed slice queue in a SynchronizedQueue
+func NewSyncSliceOf[T any](cap int) SynchronizedQueue[T] {
+	var sq Queue[T]
+	var bq SynchronizedQueue[T]
 
-	sq = NewSliceQueue(cap)
+	sq = NewSliceQueueOf[T](cap)
 
-	bq = NewSynchronizedQueue(sq) 
+	bq = NewSynchronizedQueueOf(sq)
 
 	return bq
 }
+
+func NewSliceQueue(cap int) Queue[interface{}] {
+	return NewSliceQueueOf[interface{}](cap)
+}
+
+func NewSyncSlice(cap int) SynchronizedQueue[interface{}] {
+	return NewSyncSliceOf[interface{}](cap)
+}
@@ -0,0 +1,211 @@
+package queue
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// number of times a blocked Put or Get yields before it parks
+const spscSpins = 64
+
+// the top bit of tail is set by Close, so a Put can't publish
+// an element once the consumer may have seen the queue closed
+const spscClosed uint64 = 1 << 63
+
+// cacheLinePad keeps the fields around it on separate cache lines
+// so the producer and the consumer don't false-share
+type cacheLinePad [64]byte
+
+// SPSCQueue is a bounded queue for exactly one producer and one consumer.
+// head and tail are atomic indices into a power-of-two ring buffer, so
+// TryPut and TryGet never take a lock. Put and Get spin for a while when
+// the queue is full or empty and then park on a condition variable.
+//
+// only one goroutine may call Put/TryPut and only one may call Get/TryGet
+type SPSCQueue[T any] struct {
+	_         cacheLinePad
+	head      atomic.Uint64 // next slot to read, written by the consumer
+	_         cacheLinePad
+	tail      atomic.Uint64 // next slot to write, written by the producer, plus the closed bit
+	_         cacheLinePad
+	buffer    []T         // data
+	mask      uint64      // capacity - 1, for wrapping the indices
+	putparked atomic.Bool // the producer is parked on putcv
+	getparked atomic.Bool // the consumer is parked on getcv
+	mtx       sync.Mutex  // only used for parking
+	putcv     *sync.Cond  // a condition variable for a parked Put
+	getcv     *sync.Cond  // a condition variable for a parked Get
+}
+
+// TryPut adds an element onto the tail queue
+// if the queue is full, an error is returned
+func (q *SPSCQueue[T]) TryPut(value T) error {
+	tail := q.tail.Load()
+	if tail&spscClosed != 0 {
+		return ErrClosed
+	}
+	if tail-q.head.Load() == uint64(len(q.buffer)) {
+		return ErrFull
+	}
+
+	// the slot is published by the swap of tail, which
+	// fails if Close got in first
+	q.buffer[tail&q.mask] = value
+	if !q.tail.CompareAndSwap(tail, tail+1) {
+		var zero T
+		q.buffer[tail&q.mask] = zero
+		return ErrClosed
+	}
+
+	q.unpark(&q.getparked, q.getcv)
+
+	return nil
+}
+
+// Put adds an element onto the tail queue
+// if the queue is full the function spins and then blocks
+func (q *SPSCQueue[T]) Put(value T) error {
+	for i := 0; ; i++ {
+		err := q.TryPut(value)
+		if !errors.Is(err, ErrFull) {
+			return err
+		}
+
+		if i < spscSpins {
+			runtime.Gosched()
+			continue
+		}
+
+		q.park(&q.putparked, q.putcv, func() bool {
+			return q.Len() == len(q.buffer) && !q.isClosed()
+		})
+	}
+}
+
+// TryGet gets a value or returns an error if the queue is empty
+func (q *SPSCQueue[T]) TryGet() (T, error) {
+	var zero T
+
+	head := q.head.Load()
+	tail := q.tail.Load()
+	if head == tail&^spscClosed {
+		// once closed nothing more can be published
+		if tail&spscClosed != 0 {
+			return zero, ErrClosed
+		}
+		return zero, ErrEmpty
+	}
+
+	// the slot is handed back by the store to head
+	value := q.buffer[head&q.mask]
+	q.buffer[head&q.mask] = zero
+	q.head.Store(head + 1)
+
+	q.unpark(&q.putparked, q.putcv)
+
+	return value, nil
+}
+
+// Get returns an element from the head of the queue
+// if the queue is empty the function spins and then blocks
+// once the queue is closed and drained ErrClosed is returned
+func (q *SPSCQueue[T]) Get() (T, error) {
+	for i := 0; ; i++ {
+		value, err := q.TryGet()
+		if !errors.Is(err, ErrEmpty) {
+			return value, err
+		}
+
+		if i < spscSpins {
+			runtime.Gosched()
+			continue
+		}
+
+		q.park(&q.getparked, q.getcv, func() bool {
+			return q.Len() == 0 && !q.isClosed()
+		})
+	}
+}
+
+// park blocks on cv while wait reports true.
+// the flag is set before wait is checked and the other side checks
+// the flag after it moves an index, so one of them sees the other
+func (q *SPSCQueue[T]) park(flag *atomic.Bool, cv *sync.Cond, wait func() bool) {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	flag.Store(true)
+	for wait() {
+		cv.Wait()
+	}
+	flag.Store(false)
+}
+
+// unpark wakes the other side if it is parked
+func (q *SPSCQueue[T]) unpark(flag *atomic.Bool, cv *sync.Cond) {
+	if flag.Load() {
+		q.mtx.Lock()
+		cv.Broadcast()
+		q.mtx.Unlock()
+	}
+}
+
+// isClosed reports whether Close has been called
+func (q *SPSCQueue[T]) isClosed() bool {
+	return q.tail.Load()&spscClosed != 0
+}
+
+// Len is the current number of elements in the queue
+func (q *SPSCQueue[T]) Len() int {
+	// head first, so tail can't be behind it
+	head := q.head.Load()
+	return int(q.tail.Load()&^spscClosed - head)
+}
+
+// Cap is the maximum number of elements the queue can hold
+func (q *SPSCQueue[T]) Cap() int {
+	return len(q.buffer)
+}
+
+// Close stops the queue from accepting more elements
+// and wakes up a parked Put or Get.
+// elements already in the queue can still be drained by Get
+func (q *SPSCQueue[T]) Close() {
+	for {
+		tail := q.tail.Load()
+		if tail&spscClosed != 0 || q.tail.CompareAndSwap(tail, tail|spscClosed) {
+			break
+		}
+	}
+
+	q.mtx.Lock()
+	q.putcv.Broadcast()
+	q.getcv.Broadcast()
+	q.mtx.Unlock()
+}
+
+// String
+func (q *SPSCQueue[T]) String() string {
+	return fmt.Sprintf("SPSCQueue Len:%v Cap:%v", q.Len(), q.Cap())
+}
+
+// NewSPSCQueueOf is a factory for creating single-producer
+// single-consumer queues. the capacity is rounded up to a power of two
+func NewSPSCQueueOf[T any](size int) *SPSCQueue[T] {
+	var q SPSCQueue[T]
+
+	capacity := 1
+	for capacity < size {
+		capacity <<= 1
+	}
+
+	q.buffer = make([]T, capacity)
+	q.mask = uint64(capacity - 1)
+	q.putcv = sync.NewCond(&q.mtx)
+	q.getcv = sync.NewCond(&q.mtx)
+
+	return &q
+}
@@ -0,0 +1,145 @@
+package queue
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// smallest ring a StealDeque starts with
+const stealMinSize = 32
+
+// stealRing is the circular array of a StealDeque. a full ring is
+// replaced by a bigger copy, never written in place, so a thief still
+// holding the old one reads the same elements
+type stealRing[T any] struct {
+	slots []atomic.Pointer[T]
+	mask  int64 // len(slots) - 1, for wrapping the indices
+}
+
+func newStealRing[T any](size int64) *stealRing[T] {
+	return &stealRing[T]{make([]atomic.Pointer[T], size), size - 1}
+}
+
+func (r *stealRing[T]) load(i int64) *T {
+	return r.slots[i&r.mask].Load()
+}
+
+func (r *stealRing[T]) store(i int64, p *T) {
+	r.slots[i&r.mask].Store(p)
+}
+
+// grow copies the elements from top to bottom into a ring twice the size
+func (r *stealRing[T]) grow(top, bottom int64) *stealRing[T] {
+	bigger := newStealRing[T](2 * int64(len(r.slots)))
+	for i := top; i < bottom; i++ {
+		bigger.store(i, r.load(i))
+	}
+	return bigger
+}
+
+// StealDeque is a Chase-Lev work-stealing deque. one goroutine owns it
+// and pushes and pops at the bottom like a stack, while any number of
+// thieves take the oldest elements from the top. none of them takes
+// a lock: the owner only contends with thieves for the last element.
+// the ring grows as needed, so the deque is unbounded.
+//
+// only the owner may call Push and Pop. anyone may call Steal and Len
+type StealDeque[T any] struct {
+	_      cacheLinePad
+	top    atomic.Int64 // next element to steal, written by thieves
+	_      cacheLinePad
+	bottom atomic.Int64 // next slot to push, written by the owner
+	_      cacheLinePad
+	ring   atomic.Pointer[stealRing[T]] // data
+}
+
+// Push adds an element at the bottom. owner only
+func (d *StealDeque[T]) Push(value T) {
+	b := d.bottom.Load()
+	t := d.top.Load()
+	r := d.ring.Load()
+
+	// out of room, move to a bigger ring
+	if b-t > r.mask {
+		r = r.grow(t, b)
+		d.ring.Store(r)
+	}
+
+	// the slot is published by the store to bottom
+	r.store(b, &value)
+	d.bottom.Store(b + 1)
+}
+
+// Pop removes the element at the bottom, the newest one. owner only
+// if the deque is empty, an error is returned
+func (d *StealDeque[T]) Pop() (T, error) {
+	var zero T
+
+	// claim the bottom slot before looking at top
+	b := d.bottom.Load() - 1
+	r := d.ring.Load()
+	d.bottom.Store(b)
+
+	t := d.top.Load()
+	if t > b {
+		// it was empty, put bottom back
+		d.bottom.Store(b + 1)
+		return zero, ErrEmpty
+	}
+
+	p := r.load(b)
+	if t == b {
+		// the last element, race the thieves for it
+		won := d.top.CompareAndSwap(t, t+1)
+		d.bottom.Store(b + 1)
+		if !won {
+			return zero, ErrEmpty
+		}
+	}
+
+	// release the reference held by the slot
+	r.store(b, nil)
+
+	return *p, nil
+}
+
+// Steal removes the element at the top, the oldest one
+// if the deque is empty, an error is returned
+func (d *StealDeque[T]) Steal() (T, error) {
+	var zero T
+
+	for {
+		t := d.top.Load()
+		b := d.bottom.Load()
+		if t >= b {
+			return zero, ErrEmpty
+		}
+
+		// read the element before claiming it, the owner may reuse
+		// the slot as soon as top moves on
+		p := d.ring.Load().load(t)
+		if d.top.CompareAndSwap(t, t+1) {
+			return *p, nil
+		}
+		// lost to another thief or the owner, try again
+	}
+}
+
+// Len is the number of elements, a snapshot when others are active
+func (d *StealDeque[T]) Len() int {
+	return int(max(0, d.bottom.Load()-d.top.Load()))
+}
+
+// String
+func (d *StealDeque[T]) String() string {
+	return fmt.Sprintf("StealDeque Len:%v", d.Len())
+}
+
+// NewStealDequeOf creates a work-stealing deque of elements of type T
+func NewStealDequeOf[T any]() *StealDeque[T] {
+	var d StealDeque[T]
+
+	d.ring.Store(newStealRing[T](stealMinSize))
+
+	return &d
+}
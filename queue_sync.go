@@ -1,102 +1,236 @@
 package queue
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"log"
 	"sync"
+	"time"
 )
 
 // SynchronizedQueueImpl is an implementation of the SynchronizedQueue interface
 // using a Mutex and 2 condition variables.
-type SynchronizedQueueImpl struct {
-	queue Queue	    // some data structure for backing the queue
-	mtx sync.Mutex      // a mutex for mutual exclusion
-	putcv *sync.Cond    // a condition variable for controlling Puts
-	getcv *sync.Cond    // a condition variable for controlling Gets
+type SynchronizedQueueImpl[T any] struct {
+	queue     Queue[T]       // some data structure for backing the queue
+	mtx       sync.Mutex     // a mutex for mutual exclusion
+	putcv     *sync.Cond     // a condition variable for controlling Puts
+	getcv     *sync.Cond     // a condition variable for controlling Gets
+	closed    bool           // no more Puts are accepted once set
+	bcastgets int            // number of waiters on getcv that need a Broadcast
+	policy    OverflowPolicy // what a Put does when the queue is full
+	evict     func(value T)  // called with each element the policy drops
+	dropped   int            // number of elements the policy dropped
 }
 
 // TryPut adds an element onto the tail queue
 // if the queue is full, an error is returned
-func (sq *SynchronizedQueueImpl) TryPut(value interface{}) error {
+func (sq *SynchronizedQueueImpl[T]) TryPut(value T) error {
+	return sq.tryPut(value, sq.queue.Push)
+}
+
+// tryPut does the work of TryPut, adding the element with push
+// so wrappers can use their own insert on the backend
+func (sq *SynchronizedQueueImpl[T]) tryPut(value T, push func(T) error) error {
 	// lock the mutex
-	sq.putcv.L.Lock();
+	sq.putcv.L.Lock()
 	defer sq.putcv.L.Unlock()
 
+	// is queue closed ?
+	if sq.closed {
+		return ErrClosed
+	}
+
 	// is queue full ?
 	if sq.queue.Len() == sq.queue.Cap() {
-		// return an error
-		e := errors.New("queue is full")
-		return e;
+		// return an error, unless the policy makes room
+		if sq.policy == OverflowBlock {
+			return ErrFull
+		}
+		return sq.overflow(value, push)
 	}
 
 	// queue had room, add it at the tail
 	// ==> enqueueing a value
-	sq.queue.Push(value)
+	if err := push(value); err != nil {
+		return err
+	}
 
 	// signal a Get to wake up
-	sq.getcv.Signal()
-	
+	sq.wakeGets(1)
+
 	// no error
 	return nil
-} 
+}
 
 // Put adds an element onto the tail queue
 // if the queue is full the function blocks
-func (sq *SynchronizedQueueImpl) Put(value interface{})  {
+func (sq *SynchronizedQueueImpl[T]) Put(value T) error {
+	// a background context is never done
+	return sq.PutContext(context.Background(), value)
+}
+
+// PutContext adds an element onto the tail queue
+// if the queue is full the function blocks until there is room
+// or the context is done
+func (sq *SynchronizedQueueImpl[T]) PutContext(ctx context.Context, value T) error {
+	return sq.put(ctx, value, sq.queue.Push)
+}
+
+// put does the work of PutContext, adding the element with push
+// so wrappers can use their own insert on the backend
+func (sq *SynchronizedQueueImpl[T]) put(ctx context.Context, value T, push func(T) error) error {
 	// lock the mutex
 	sq.putcv.L.Lock()
 	defer sq.putcv.L.Unlock()
 
+	// only the Block policy waits for room
+	block := sq.policy == OverflowBlock
 
-	// block until a value is in the queue
-	for sq.queue.Len() == sq.queue.Cap() {
+	// wake the waiters if the context is done while blocked
+	if block && sq.queue.Len() == sq.queue.Cap() && ctx.Done() != nil {
+		stop := context.AfterFunc(ctx, broadcaster(sq.putcv))
+		defer stop()
+	}
+
+	// block until there is room in the queue
+	for block && sq.queue.Len() == sq.queue.Cap() && !sq.closed {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		// release and wait
 		sq.putcv.Wait()
 	}
-	
+
+	// Close wakes up all blocked Puts
+	if sq.closed {
+		return ErrClosed
+	}
+
+	// still full, the policy decides
+	if sq.queue.Len() == sq.queue.Cap() {
+		return sq.overflow(value, push)
+	}
+
 	// queue has room, add it at the tail
 	// ==> enqueueing a value
-	sq.queue.Push(value)
-
+	if err := push(value); err != nil {
+		return err
+	}
 
 	// signal a Get to wake up
-	sq.getcv.Signal()
-} 
+	sq.wakeGets(1)
+
+	return nil
+}
+
+// PutTimeout adds an element onto the tail queue
+// if the queue is full the function blocks for at most d
+// and returns ErrTimeout if there is still no room
+func (sq *SynchronizedQueueImpl[T]) PutTimeout(value T, d time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+
+	return timeoutError(sq.PutContext(ctx, value))
+}
 
 // Get returns an element from the head of the queue
 // if the queue is empty,the caller blocks
-func (sq *SynchronizedQueueImpl) Get() interface{} {
-	var value interface{}
+// once the queue is closed and drained ErrClosed is returned
+func (sq *SynchronizedQueueImpl[T]) Get() (T, error) {
+	// a background context is never done
+	return sq.GetContext(context.Background())
+}
+
+// GetContext returns an element from the head of the queue
+// if the queue is empty,the caller blocks until there is an element
+// or the context is done
+func (sq *SynchronizedQueueImpl[T]) GetContext(ctx context.Context) (T, error) {
+	return sq.get(ctx, sq.queue.Pop)
+}
+
+// get does the work of GetContext, taking the element with pop
+// so wrappers can use their own removal from the backend
+func (sq *SynchronizedQueueImpl[T]) get(ctx context.Context, pop func() (T, error)) (T, error) {
+	var zero T
 
 	// lock the mutex
 	sq.getcv.L.Lock()
 	defer sq.getcv.L.Unlock()
 
-	// block until a value is in the queue
-	for sq.queue.Len() == 0 {
-		// release and wait
-		sq.getcv.Wait()
+	// wake the waiters if the context is done while blocked
+	if ctx.Done() != nil {
+		stop := context.AfterFunc(ctx, broadcaster(sq.getcv))
+		defer stop()
 	}
 
-	// at this point there is at least one item in the queue
-	// ==> dequeuing a value
-	// ...
-	value, err := sq.queue.Pop()
-	if err != nil {
-		log.Fatal(err)
+	for {
+		// block until a value is in the queue
+		for sq.queue.Len() == 0 && !sq.closed {
+			if err := ctx.Err(); err != nil {
+				return zero, err
+			}
+			// release and wait
+			sq.getcv.Wait()
+		}
+
+		// closed and nothing left to drain
+		if sq.queue.Len() == 0 {
+			return zero, ErrClosed
+		}
+
+		// at this point there is at least one item in the queue
+		// ==> dequeuing a value
+		// ...
+		before := sq.queue.Len()
+		value, err := pop()
+
+		// signal a Put to wake up
+		sq.wakePuts(before)
+
+		// the backend dropped what it had, e.g. expired elements
+		if errors.Is(err, ErrEmpty) {
+			continue
+		}
+		if err != nil {
+			return zero, err
+		}
+
+		return value, nil
 	}
+}
+
+// GetTimeout returns an element from the head of the queue
+// if the queue is empty the function blocks for at most d
+// and returns ErrTimeout if there is still no element
+func (sq *SynchronizedQueueImpl[T]) GetTimeout(d time.Duration) (T, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
 
-	// signal a Put to wake up
-	sq.putcv.Signal()
+	value, err := sq.GetContext(ctx)
+	return value, timeoutError(err)
+}
 
-	return value
+// broadcaster returns a function that wakes all waiters on cv.
+// the mutex is taken so the wakeup can't be lost between
+// a waiter checking its context and calling Wait
+func broadcaster(cv *sync.Cond) func() {
+	return func() {
+		cv.L.Lock()
+		cv.Broadcast()
+		cv.L.Unlock()
+	}
 }
 
 // TryGet attempts to get a value
 // if the queue is empty returns an error
-func (sq *SynchronizedQueueImpl) TryGet() (interface{}, error) {
-	var value interface{}
+func (sq *SynchronizedQueueImpl[T]) TryGet() (T, error) {
+	return sq.tryGet(sq.queue.Pop)
+}
+
+// tryGet does the work of TryGet, taking the element with pop
+// so wrappers can use their own removal from the backend
+func (sq *SynchronizedQueueImpl[T]) tryGet(pop func() (T, error)) (T, error) {
+	var value T
 	var err error
 
 	// lock the mutex
@@ -104,64 +238,408 @@ func (sq *SynchronizedQueueImpl) TryGet() (interface{}, error) {
 	defer sq.getcv.L.Unlock()
 
 	// does the queue have elements?
-	if sq.queue.Len() > 0 {
-		value, err = sq.queue.Pop()
-		if err != nil {
-			log.Fatal(err)
+	before := sq.queue.Len()
+	if before > 0 {
+		value, err = pop()
+
+		// signal a Put to wake up
+		sq.wakePuts(before)
+	}
+
+	// nothing there, or the backend dropped what it had
+	if before == 0 || errors.Is(err, ErrEmpty) {
+		if sq.closed {
+			err = ErrClosed
+		} else {
+			err = ErrEmpty
 		}
-	} else {
-		value = nil
-		err = errors.New("queue is empty");
 	}
 
-	// signal a Put to wake up
-	sq.putcv.Signal()
-	
 	// unlock the mutex
 	return value, err
 }
 
-// Len is the current number of elements in the queue 
-func (sq *SynchronizedQueueImpl) Len() int {
+// PutMany adds as many of values as there is room for onto the tail queue
+// under a single lock hold. ErrFull is returned if not all of them fit
+func (sq *SynchronizedQueueImpl[T]) PutMany(values []T) (int, error) {
+	// lock the mutex
+	sq.putcv.L.Lock()
+	defer sq.putcv.L.Unlock()
+
+	// is queue closed ?
+	if sq.closed {
+		return 0, ErrClosed
+	}
+
+	n, err := sq.pushMany(values)
+	sq.wakeGets(n)
+
+	if err == nil && n < len(values) {
+		return sq.overflowMany(n, values[n:])
+	}
+	return n, err
+}
+
+// PutManyContext adds values onto the tail queue under a single lock hold
+// if the queue is full the function blocks until there is room for
+// at least one of them or the context is done
+func (sq *SynchronizedQueueImpl[T]) PutManyContext(ctx context.Context, values []T) (int, error) {
+	if len(values) == 0 {
+		return 0, nil
+	}
+
+	// lock the mutex
+	sq.putcv.L.Lock()
+	defer sq.putcv.L.Unlock()
+
+	// only the Block policy waits for room
+	block := sq.policy == OverflowBlock
+
+	// wake the waiters if the context is done while blocked
+	if block && sq.queue.Len() == sq.queue.Cap() && ctx.Done() != nil {
+		stop := context.AfterFunc(ctx, broadcaster(sq.putcv))
+		defer stop()
+	}
+
+	// block until there is room in the queue
+	for block && sq.queue.Len() == sq.queue.Cap() && !sq.closed {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		// release and wait
+		sq.putcv.Wait()
+	}
+
+	// Close wakes up all blocked Puts
+	if sq.closed {
+		return 0, ErrClosed
+	}
+
+	n, err := sq.pushMany(values)
+	sq.wakeGets(n)
+
+	// the Block policy is happy with at least one
+	if err == nil && n < len(values) && (n == 0 || !block) {
+		return sq.overflowMany(n, values[n:])
+	}
+	return n, err
+}
+
+// restore puts all of values on the tail queue or none of them
+// if there isn't room. the times of a snapshot are dropped
+func (sq *SynchronizedQueueImpl[T]) restore(values []T, times []time.Time, kind snapshotTimes) (int, error) {
+	return sq.putAll(len(values), func() (int, error) { return sq.pushMany(values) })
+}
+
+// putAll runs push under the lock if there is room for n elements
+// and wakes the Gets for the ones it added. ErrFull is returned
+// without calling push if there isn't
+func (sq *SynchronizedQueueImpl[T]) putAll(n int, push func() (int, error)) (int, error) {
+	// lock the mutex
+	sq.putcv.L.Lock()
+	defer sq.putcv.L.Unlock()
+
+	// is queue closed ?
+	if sq.closed {
+		return 0, ErrClosed
+	}
+
+	// is there room for all of them ?
+	if n > sq.queue.Cap()-sq.queue.Len() {
+		return 0, ErrFull
+	}
+
+	n, err := push()
+	sq.wakeGets(n)
+
+	return n, err
+}
+
+// GetMany copies up to max elements from the head of the queue into dst
+// under a single lock hold. ErrEmpty is returned if there were none
+func (sq *SynchronizedQueueImpl[T]) GetMany(dst []T, max int) (int, error) {
+	_, max = batchLimits(len(dst), 0, max)
+
+	// lock the mutex
+	sq.getcv.L.Lock()
+	defer sq.getcv.L.Unlock()
+
+	n, err := sq.popMany(dst[:max])
+	if err != nil {
+		return n, err
+	}
+	if n == 0 && max > 0 {
+		if sq.closed {
+			return 0, ErrClosed
+		}
+		return 0, ErrEmpty
+	}
+	return n, nil
+}
+
+// GetManyContext copies up to max elements from the head of the queue
+// into dst under a single lock hold. if fewer than min elements are
+// in the queue the function blocks until there are or the context is done.
+// a closed queue returns whatever is left
+func (sq *SynchronizedQueueImpl[T]) GetManyContext(ctx context.Context, dst []T, min, max int) (int, error) {
+	min, max = batchLimits(len(dst), min, max)
+	if max == 0 {
+		return 0, nil
+	}
+
+	// lock the mutex
+	sq.getcv.L.Lock()
+	defer sq.getcv.L.Unlock()
+
+	// wake the waiters if the context is done while blocked
+	if ctx.Done() != nil {
+		stop := context.AfterFunc(ctx, broadcaster(sq.getcv))
+		defer stop()
+	}
+
+	// Puts broadcast instead of signal while someone waits for a batch
+	// so a single wakeup can't be swallowed by a waiter that needs more
+	if min > 1 {
+		sq.bcastgets++
+		defer func() { sq.bcastgets-- }()
+	}
+
+	for {
+		// block until enough values are in the queue
+		for sq.queue.Len() < min && !sq.closed {
+			if err := ctx.Err(); err != nil {
+				return 0, err
+			}
+			// release and wait
+			sq.getcv.Wait()
+		}
+
+		// closed and nothing left to drain
+		if sq.queue.Len() == 0 {
+			return 0, ErrClosed
+		}
+
+		// go back to waiting if the backend dropped everything it had
+		n, err := sq.popMany(dst[:max])
+		if n > 0 || err != nil {
+			return n, err
+		}
+	}
+}
+
+// Peek returns the element at the head of the queue without removing it
+// if the queue is empty the caller blocks
+// once the queue is closed and drained ErrClosed is returned
+func (sq *SynchronizedQueueImpl[T]) Peek() (T, error) {
+	var zero T
+
+	// lock the mutex
+	sq.getcv.L.Lock()
+	defer sq.getcv.L.Unlock()
+
+	// a Peek doesn't consume the element it is woken for,
+	// so Puts broadcast to make sure a Get is woken too
+	sq.bcastgets++
+	defer func() { sq.bcastgets-- }()
+
+	for {
+		// block until a value is in the queue
+		for sq.queue.Len() == 0 && !sq.closed {
+			// release and wait
+			sq.getcv.Wait()
+		}
+
+		// closed and nothing left to drain
+		if sq.queue.Len() == 0 {
+			return zero, ErrClosed
+		}
+
+		before := sq.queue.Len()
+		value, err := sq.queue.Peek()
+		sq.wakePuts(before)
+
+		// the backend dropped what it had, e.g. expired elements
+		if !errors.Is(err, ErrEmpty) {
+			return value, err
+		}
+	}
+}
+
+// TryPeek returns the element at the head of the queue without removing it
+// if the queue is empty returns an error
+func (sq *SynchronizedQueueImpl[T]) TryPeek() (T, error) {
+	var zero T
+
+	// lock the mutex
+	sq.getcv.L.Lock()
+	defer sq.getcv.L.Unlock()
+
+	before := sq.queue.Len()
+	if before > 0 {
+		value, err := sq.queue.Peek()
+		sq.wakePuts(before)
+
+		// the backend may have dropped what it had
+		if !errors.Is(err, ErrEmpty) {
+			return value, err
+		}
+	}
+
+	if sq.closed {
+		return zero, ErrClosed
+	}
+	return zero, ErrEmpty
+}
+
+// PeekN returns up to n elements from the head of the queue
+// without removing them
+func (sq *SynchronizedQueueImpl[T]) PeekN(n int) []T {
+	// lock the mutex
+	sq.getcv.L.Lock()
+	defer sq.getcv.L.Unlock()
+
+	return peekN(sq.queue, n)
+}
+
+// pushMany moves values into the backend, natively if it supports batches.
+// running out of room is not an error here.
+// the caller must hold the mutex
+func (sq *SynchronizedQueueImpl[T]) pushMany(values []T) (int, error) {
+	if bq, ok := sq.queue.(batchQueue[T]); ok {
+		return bq.PushMany(values), nil
+	}
+
+	for i, value := range values {
+		if sq.queue.Len() == sq.queue.Cap() {
+			return i, nil
+		}
+		if err := sq.queue.Push(value); err != nil {
+			return i, err
+		}
+	}
+	return len(values), nil
+}
+
+// popMany moves up to len(dst) elements out of the backend,
+// natively if it supports batches and wakes up the blocked Puts.
+// the caller must hold the mutex
+func (sq *SynchronizedQueueImpl[T]) popMany(dst []T) (int, error) {
+	var n int
+	var err error
+
+	before := sq.queue.Len()
+	if bq, ok := sq.queue.(batchQueue[T]); ok {
+		n = bq.PopMany(dst)
+	} else {
+		for n < len(dst) && sq.queue.Len() > 0 {
+			dst[n], err = sq.queue.Pop()
+			if err != nil {
+				// the backend dropped the rest, it isn't an error
+				if errors.Is(err, ErrEmpty) {
+					err = nil
+				}
+				break
+			}
+			n++
+		}
+	}
+
+	sq.wakePuts(before)
+
+	return n, err
+}
+
+// wakePuts wakes the Puts after the queue shrank from before elements.
+// that is usually the one element got but a backend may drop more
+func (sq *SynchronizedQueueImpl[T]) wakePuts(before int) {
+	wake(sq.putcv, before-sq.queue.Len())
+}
+
+// wakeGets wakes the Gets after n elements were added
+func (sq *SynchronizedQueueImpl[T]) wakeGets(n int) {
+	if sq.bcastgets > 0 {
+		sq.getcv.Broadcast()
+		return
+	}
+	wake(sq.getcv, n)
+}
+
+// wake signals one waiter on cv when one element moved
+// and all of them when several did
+func wake(cv *sync.Cond, n int) {
+	switch {
+	case n == 1:
+		cv.Signal()
+	case n > 1:
+		cv.Broadcast()
+	}
+}
+
+// Len is the current number of elements in the queue
+func (sq *SynchronizedQueueImpl[T]) Len() int {
 	return sq.queue.Len()
 }
 
 // Cap is the maximum number of elements the queue can hold
-func (sq *SynchronizedQueueImpl) Cap() int {
+func (sq *SynchronizedQueueImpl[T]) Cap() int {
 	return sq.queue.Cap()
 }
 
-// Close handles any required cleanup
-func (sq *SynchronizedQueueImpl) Close() {
-	// noop
+// Close stops the queue from accepting more elements
+// and wakes up every blocked Put and Get.
+// elements already in the queue can still be drained by Get
+func (sq *SynchronizedQueueImpl[T]) Close() {
+	sq.mtx.Lock()
+	defer sq.mtx.Unlock()
+
+	sq.closed = true
+	sq.putcv.Broadcast()
+	sq.getcv.Broadcast()
 }
 
 // String
-func (sq *SynchronizedQueueImpl) String() string {
+func (sq *SynchronizedQueueImpl[T]) String() string {
 	var s string = "SynchronizedQueue"
 	if sq.queue != nil {
-		s = fmt.Sprintf("%s:%s",s,sq.queue.String())
+		s = fmt.Sprintf("%s:%s", s, sq.queue.String())
 	} else {
-		s = fmt.Sprintf("%s : no queue",s);
+		s = fmt.Sprintf("%s : no queue", s)
 	}
 	return s
 }
 
-
-// NewSynchronizedQueue is a factory for creating bounded queues
-// that use a mutex and condition variable
+// NewSynchronizedQueueOf is a factory for creating bounded queues
+// of elements of type T that use a mutex and condition variable
 // returns an instance of SynchronizedQueue
-func NewSynchronizedQueue(q Queue) SynchronizedQueue {
-	var sq SynchronizedQueueImpl
-	
+func NewSynchronizedQueueOf[T any](q Queue[T]) SynchronizedQueue[T] {
+	return newSynchronizedQueueImpl(q)
+}
+
+// newSynchronizedQueueImpl does the work of NewSynchronizedQueueOf
+// for the wrappers that need the concrete type
+func newSynchronizedQueueImpl[T any](q Queue[T]) *SynchronizedQueueImpl[T] {
+	var sq SynchronizedQueueImpl[T]
+
 	// attach the underlying queue data structure
 	sq.queue = q
 
 	// both condition variables get the same mutex
 	// but wakeups go from put to get and vice versa
-	sq.mtx = sync.Mutex{} 
+	sq.mtx = sync.Mutex{}
 	sq.putcv = sync.NewCond(&sq.mtx)
 	sq.getcv = sync.NewCond(&sq.mtx)
 
+	// a backend with an overflow policy of its own applies it
+	if oq, ok := q.(overflowQueue); ok && oq.overflowPolicy() != OverflowBlock {
+		sq.policy = overflowBackend
+	}
+
 	return &sq
 }
+
+// NewSynchronizedQueue is a factory for creating bounded queues
+// that use a mutex and condition variable
+// returns an instance of SynchronizedQueue
+func NewSynchronizedQueue(q Queue[interface{}]) SynchronizedQueue[interface{}] {
+	return NewSynchronizedQueueOf(q)
+}
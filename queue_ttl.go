@@ -0,0 +1,259 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// TTLItem is an element of a TTLQueue and the time it goes stale.
+// it is what the backend of a TTLQueue holds
+type TTLItem[T any] struct {
+	value   T
+	expires time.Time // zero for an element that never goes stale
+}
+
+// TTLOptions configures a TTLQueue
+type TTLOptions[T any] struct {
+	// time to live of the elements added by Push and Put, 0 for forever.
+	// a negative one makes them fail
+	TTL time.Duration
+
+	// called with each element that is dropped because it went stale.
+	// it runs under the lock of a synchronized queue, so it must not
+	// call back into the same queue
+	OnExpire func(value T)
+
+	// stale elements are also handed to this queue with TryPut,
+	// and dropped if it is full. it must not be the same queue
+	Expired SynchronizedQueue[T]
+
+	// the clock, time.Now when nil
+	Now func() time.Time
+}
+
+// TTLQueue wraps any Queue backend and gives each element a time to live.
+// elements that go stale are dropped when they reach the head of the
+// queue instead of being returned by Pop or Peek, and are counted.
+// until then they still take up room and are counted by Len
+type TTLQueue[T any] struct {
+	queue   Queue[TTLItem[T]] // the backend
+	options TTLOptions[T]     // the default TTL and where stale elements go
+	expired int               // number of elements dropped so far
+}
+
+// now reads the clock
+func (tq *TTLQueue[T]) now() time.Time {
+	if tq.options.Now == nil {
+		return time.Now()
+	}
+	return tq.options.Now()
+}
+
+// stale reports whether item has expired at time now
+func (item TTLItem[T]) stale(now time.Time) bool {
+	return !item.expires.IsZero() && !now.Before(item.expires)
+}
+
+func (tq *TTLQueue[T]) Len() int {
+	return tq.queue.Len()
+}
+
+func (tq *TTLQueue[T]) Cap() int {
+	return tq.queue.Cap()
+}
+
+// Push adds an element with the default time to live
+func (tq *TTLQueue[T]) Push(value T) error {
+	return tq.PushTTL(value, tq.options.TTL)
+}
+
+// PushTTL adds an element that goes stale after ttl, 0 for never.
+// a negative ttl is an error
+func (tq *TTLQueue[T]) PushTTL(value T, ttl time.Duration) error {
+	if ttl < 0 {
+		return fmt.Errorf("ttl must not be negative, not %v", ttl)
+	}
+
+	item := TTLItem[T]{value: value}
+	if ttl > 0 {
+		item.expires = tq.now().Add(ttl)
+	}
+	return tq.queue.Push(item)
+}
+
+// Pop returns the first element that hasn't gone stale
+// dropping the stale ones in front of it
+func (tq *TTLQueue[T]) Pop() (T, error) {
+	var zero T
+
+	now := tq.now()
+	for {
+		item, err := tq.queue.Pop()
+		if err != nil {
+			return zero, err
+		}
+		if !item.stale(now) {
+			return item.value, nil
+		}
+		tq.expire(item.value)
+	}
+}
+
+// Peek returns the first element that hasn't gone stale
+// without removing it. the stale ones in front of it are dropped
+func (tq *TTLQueue[T]) Peek() (T, error) {
+	var zero T
+
+	now := tq.now()
+	for {
+		item, err := tq.queue.Peek()
+		if err != nil {
+			return zero, err
+		}
+		if !item.stale(now) {
+			return item.value, nil
+		}
+		tq.queue.Pop()
+		tq.expire(item.value)
+	}
+}
+
+// PeekN returns up to n elements that haven't gone stale
+// without removing them
+func (tq *TTLQueue[T]) PeekN(n int) []T {
+	now := tq.now()
+
+	values := make([]T, 0, max(0, min(n, tq.queue.Len())))
+	for _, item := range peekN(tq.queue, tq.queue.Len()) {
+		if len(values) == n {
+			break
+		}
+		if !item.stale(now) {
+			values = append(values, item.value)
+		}
+	}
+	return values
+}
+
+// expire counts a stale element and hands it on
+func (tq *TTLQueue[T]) expire(value T) {
+	tq.expired++
+
+	if tq.options.OnExpire != nil {
+		tq.options.OnExpire(value)
+	}
+	if tq.options.Expired != nil {
+		tq.options.Expired.TryPut(value)
+	}
+}
+
+// Expired is the number of elements dropped because they went stale
+func (tq *TTLQueue[T]) Expired() int {
+	return tq.expired
+}
+
+// String
+func (tq *TTLQueue[T]) String() string {
+	return fmt.Sprintf("TTLQueue Len:%v Cap:%v Expired:%v", tq.Len(), tq.Cap(), tq.Expired())
+}
+
+// NewTTLQueueOf wraps the backend q so its elements have a time to live
+func NewTTLQueueOf[T any](q Queue[TTLItem[T]], options TTLOptions[T]) *TTLQueue[T] {
+	var tq TTLQueue[T]
+
+	tq.queue = q
+	tq.options = options
+
+	return &tq
+}
+
+// SyncTTLQueue is a TTLQueue wrapped in a SynchronizedQueue.
+// Put uses the default time to live and PutTTL sets it per element
+type SyncTTLQueue[T any] struct {
+	*SynchronizedQueueImpl[T]
+	tq *TTLQueue[T] // the backend, for the time to live operations
+}
+
+// PutTTL adds an element that goes stale after ttl, 0 for never
+// a negative ttl is an error
+// if the queue is full the function blocks
+func (stq *SyncTTLQueue[T]) PutTTL(value T, ttl time.Duration) error {
+	return stq.PutTTLContext(context.Background(), value, ttl)
+}
+
+// PutTTLContext adds an element that goes stale after ttl, 0 for never
+// a negative ttl is an error
+// if the queue is full the function blocks until there is room
+// or the context is done
+func (stq *SyncTTLQueue[T]) PutTTLContext(ctx context.Context, value T, ttl time.Duration) error {
+	return stq.put(ctx, value, func(value T) error { return stq.tq.PushTTL(value, ttl) })
+}
+
+// TryPutTTL adds an element that goes stale after ttl, 0 for never
+// a negative ttl is an error
+// if the queue is full, an error is returned
+func (stq *SyncTTLQueue[T]) TryPutTTL(value T, ttl time.Duration) error {
+	return stq.tryPut(value, func(value T) error { return stq.tq.PushTTL(value, ttl) })
+}
+
+// Expired is the number of elements dropped because they went stale
+func (stq *SyncTTLQueue[T]) Expired() int {
+	// lock the mutex
+	stq.mtx.Lock()
+	defer stq.mtx.Unlock()
+
+	return stq.tq.Expired()
+}
+
+// snapshot returns the elements that haven't gone stale
+// and the time each one goes stale
+func (stq *SyncTTLQueue[T]) snapshot() ([]T, []time.Time, snapshotTimes) {
+	// lock the mutex
+	stq.mtx.Lock()
+	defer stq.mtx.Unlock()
+
+	now := stq.tq.now()
+
+	items := peekN(stq.tq.queue, stq.tq.queue.Len())
+	values := make([]T, 0, len(items))
+	expires := make([]time.Time, 0, len(items))
+	for _, item := range items {
+		if !item.stale(now) {
+			values = append(values, item.value)
+			expires = append(expires, item.expires)
+		}
+	}
+	return values, expires, timesExpires
+}
+
+// restore adds all the elements of a snapshot or none of them
+// if there isn't room. each one goes stale at its time if the snapshot
+// has those times, and gets the default time to live if it doesn't
+func (stq *SyncTTLQueue[T]) restore(values []T, times []time.Time, kind snapshotTimes) (int, error) {
+	if kind != timesExpires {
+		return stq.SynchronizedQueueImpl.restore(values, times, kind)
+	}
+	return stq.putAll(len(values), func() (int, error) {
+		for i, value := range values {
+			if err := stq.tq.queue.Push(TTLItem[T]{value, times[i]}); err != nil {
+				return i, err
+			}
+		}
+		return len(values), nil
+	})
+}
+
+// NewSyncTTLOf wraps the backend q so its elements have a time to live
+// and wraps that in a SynchronizedQueue
+func NewSyncTTLOf[T any](q Queue[TTLItem[T]], options TTLOptions[T]) *SyncTTLQueue[T] {
+	var stq SyncTTLQueue[T]
+
+	// wrap the backend
+	stq.tq = NewTTLQueueOf(q, options)
+
+	// wrap it in the synchronized bounded queue
+	stq.SynchronizedQueueImpl = newSynchronizedQueueImpl[T](stq.tq)
+
+	return &stq
+}
@@ -7,41 +7,51 @@ import (
 // test variables
 const rqsize int = 100
 
-
 // ===================
 // Race Detector Tests
 // ===================
 
 func TestChannelRace(t *testing.T) {
-	async1(t,NewChannelQueue(rqsize))
-	async3(t,NewChannelQueue(rqsize))
+	async1(t, NewChannelQueue(rqsize))
+	async3(t, NewChannelQueue(rqsize))
 }
 
 func TestListRace(t *testing.T) {
-	async1(t,NewSyncList(rqsize))
-	async3(t,NewSyncList(rqsize))
+	async1(t, NewSyncList(rqsize))
+	async3(t, NewSyncList(rqsize))
 }
 func TestCircularRace(t *testing.T) {
-	async1(t,NewSyncCircular(rqsize))
-	async3(t,NewSyncCircular(rqsize))
+	async1(t, NewSyncCircular(rqsize))
+	async3(t, NewSyncCircular(rqsize))
 }
 
 func TestRingRace(t *testing.T) {
-	async1(t,NewSyncRing(rqsize))
-	async3(t,NewSyncRing(rqsize))
+	async1(t, NewSyncRing(rqsize))
+	async3(t, NewSyncRing(rqsize))
 }
 
 func TestSliceRace(t *testing.T) {
-	async1(t,NewSyncSlice(rqsize))
-	async3(t,NewSyncSlice(rqsize))
+	async1(t, NewSyncSlice(rqsize))
+	async3(t, NewSyncSlice(rqsize))
 }
 
 func TestComboRace(t *testing.T) {
-	async1(t,NewSyncCircular(rqsize))
-	async3(t,NewSyncList(rqsize))
+	async1(t, NewSyncCircular(rqsize))
+	async3(t, NewSyncList(rqsize))
 }
 
 func TestNativeRace(t *testing.T) {
-	async2(t,NewNativeQueue(rqsize))
-	async4(t,NewNativeQueue(rqsize))
-}
\ No newline at end of file
+	async2(t, NewNativeQueue(rqsize))
+	async4(t, NewNativeQueue(rqsize))
+}
+
+func TestSPSCRace(t *testing.T) {
+	async5(t, NewSPSCQueueOf[int](rqsize), false)
+	async5(t, NewSPSCQueueOf[int](rqsize), true)
+}
+
+func TestMPMCRace(t *testing.T) {
+	async1(t, NewMPMCQueue(rqsize))
+	async3(t, NewMPMCQueue(rqsize))
+	async6(t, NewMPMCQueueOf[int](rqsize), 8, 8)
+}
@@ -0,0 +1,172 @@
+package queue
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// test variables
+const rlqsize int = 8
+
+var errTest = errors.New("test failure")
+
+// a nacked item comes back at the head, an acked one is gone
+func reliable1(t *testing.T, q *ReliableQueue[int]) {
+	q.Put(1)
+	q.Put(2)
+
+	d, err := q.Receive()
+	if err != nil || d.Value() != 1 || d.Attempts() != 1 || d.LastError() != nil {
+		t.Error("Receive should deliver 1 the first time", q, d, err)
+	}
+	if q.Len() != 1 || q.InFlight() != 1 {
+		t.Error("1 should be in flight", q.Len(), q.InFlight())
+	}
+	if err := q.Nack(d.Tag(), errTest); err != nil {
+		t.Error("Nack should not fail", err)
+	}
+
+	d, err = q.TryReceive()
+	if err != nil || d.Value() != 1 || d.Attempts() != 2 || !errors.Is(d.LastError(), errTest) {
+		t.Error("TryReceive should deliver 1 again", q, d, err)
+	}
+	if err := q.Ack(d.Tag()); err != nil {
+		t.Error("Ack should not fail", err)
+	}
+	if err := q.Ack(d.Tag()); !errors.Is(err, ErrUnknownTag) {
+		t.Error("err should be ErrUnknownTag", err)
+	}
+
+	d, _ = q.Receive()
+	if d.Value() != 2 {
+		t.Error("Receive should deliver 2", d)
+	}
+	q.Ack(d.Tag())
+
+	if _, err := q.TryReceive(); !errors.Is(err, ErrEmpty) {
+		t.Error("err should be ErrEmpty", err)
+	}
+	if q.Len() != 0 || q.InFlight() != 0 {
+		t.Error("nothing should be left", q.Len(), q.InFlight())
+	}
+}
+
+// an item whose visibility timeout runs out is delivered again
+func reliable2(t *testing.T, q *ReliableQueue[int]) {
+	q.Put(1)
+
+	d, _ := q.Receive()
+	if _, err := q.TryReceive(); !errors.Is(err, ErrEmpty) {
+		t.Error("the item should be invisible", err)
+	}
+
+	again, err := q.ReceiveTimeout(time.Second)
+	if err != nil || again.Value() != 1 || again.Attempts() != 2 || !errors.Is(again.LastError(), ErrTimeout) {
+		t.Error("the item should come back after the timeout", again, err)
+	}
+	if err := q.Ack(d.Tag()); !errors.Is(err, ErrUnknownTag) {
+		t.Error("the old delivery should be unknown", err)
+	}
+	if err := q.Ack(again.Tag()); err != nil {
+		t.Error("Ack should not fail", err)
+	}
+
+	if _, err := q.ReceiveTimeout(10 * time.Millisecond); !errors.Is(err, ErrTimeout) {
+		t.Error("err should be ErrTimeout", err)
+	}
+}
+
+// a closed queue keeps delivering what comes back until nothing is in flight
+func reliable3(t *testing.T, q *ReliableQueue[int]) {
+	var wg sync.WaitGroup
+
+	q.Put(1)
+	q.Close()
+	if err := q.Put(2); !errors.Is(err, ErrClosed) {
+		t.Error("err should be ErrClosed", err)
+	}
+
+	d, _ := q.Receive()
+	if _, err := q.TryReceive(); !errors.Is(err, ErrEmpty) {
+		t.Error("err should be ErrEmpty while 1 is in flight", err)
+	}
+
+	// a blocked Receive is woken by the Nack
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		d, err := q.Receive()
+		if err != nil || d.Value() != 1 {
+			t.Error("Receive should get the nacked item", d, err)
+		}
+		q.Ack(d.Tag())
+	}()
+	time.Sleep(10 * time.Millisecond)
+	q.Nack(d.Tag(), nil)
+	wg.Wait()
+
+	if _, err := q.Receive(); !errors.Is(err, ErrClosed) {
+		t.Error("err should be ErrClosed", err)
+	}
+	if _, err := q.TryReceive(); !errors.Is(err, ErrClosed) {
+		t.Error("err should be ErrClosed", err)
+	}
+}
+
+// consumers that fail at random still get every item acked once
+func reliable4(t *testing.T, q *ReliableQueue[int]) {
+	var wg sync.WaitGroup
+	var mtx sync.Mutex
+	acked := make(map[int]int)
+	const count = 200
+
+	for c := 0; c < 4; c++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				d, err := q.Receive()
+				if err != nil {
+					return
+				}
+				if d.Value()%3 == 0 && d.Attempts() < 3 {
+					q.Nack(d.Tag(), errTest)
+					continue
+				}
+				// drop some on the floor, the timeout brings them back
+				if d.Value()%7 == 0 && d.Attempts() == 1 {
+					continue
+				}
+				mtx.Lock()
+				acked[d.Value()]++
+				mtx.Unlock()
+				q.Ack(d.Tag())
+			}
+		}()
+	}
+
+	for i := 0; i < count; i++ {
+		q.Put(i)
+	}
+	q.Close()
+	wg.Wait()
+
+	for i := 0; i < count; i++ {
+		if acked[i] != 1 {
+			t.Error("each item should be acked once", i, acked[i])
+		}
+	}
+}
+
+// RELIABLE QUEUE
+func TestReliable(t *testing.T) {
+	reliable1(t, NewReliableQueueOf(NewSyncCircularOf[int](rlqsize), time.Second))
+	reliable1(t, NewReliableQueueOf(NewChannelQueueOf[int](rlqsize), time.Second))
+	reliable1(t, NewReliableQueueOf(NewMPMCQueueOf[int](rlqsize), time.Second))
+	reliable2(t, NewReliableQueueOf(NewSyncCircularOf[int](rlqsize), 20*time.Millisecond))
+	reliable3(t, NewReliableQueueOf(NewNativeQueueOf[int](rlqsize), time.Second))
+	reliable4(t, NewReliableQueueOf(NewSyncCircularOf[int](rlqsize), 20*time.Millisecond))
+	reliable4(t, NewReliableQueueOf(NewMPMCQueueOf[int](rlqsize), 20*time.Millisecond))
+}
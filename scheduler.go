@@ -0,0 +1,225 @@
+package queue
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// Task is a unit of work run by a Scheduler. w is the worker running it,
+// for spawning more tasks onto the same worker
+type Task func(w *Worker)
+
+// Worker is one goroutine of a Scheduler with its own StealDeque
+type Worker struct {
+	id    int
+	deque *StealDeque[Task] // tasks spawned on this worker
+	sched *Scheduler
+	next  int // the next victim to steal from
+}
+
+// ID is the index of the worker, 0 to Workers()-1
+func (w *Worker) ID() int {
+	return w.id
+}
+
+// Spawn adds a task to this worker's own deque. other workers steal it
+// if this one doesn't get to it first. it may only be called from a task
+// running on w
+func (w *Worker) Spawn(task Task) {
+	w.sched.pending.Add(1)
+	w.deque.Push(task)
+	w.sched.wakeOne()
+}
+
+// find looks for a task: the newest of our own, then one submitted
+// from outside, then the oldest of another worker's
+func (w *Worker) find() (Task, bool) {
+	if task, err := w.deque.Pop(); err == nil {
+		return task, true
+	}
+	if task, err := w.sched.inject.TryGet(); err == nil {
+		w.sched.injected.Add(-1)
+		return task, true
+	}
+
+	// go round the other workers, starting where we left off
+	workers := w.sched.workers
+	for i := 1; i < len(workers); i++ {
+		w.next = (w.next + 1) % len(workers)
+		if w.next == w.id {
+			continue
+		}
+		if task, err := workers[w.next].deque.Steal(); err == nil {
+			return task, true
+		}
+	}
+	return nil, false
+}
+
+// run executes tasks until the scheduler is closed and drained
+func (w *Worker) run() {
+	s := w.sched
+	defer s.wg.Done()
+
+	for {
+		task, ok := w.find()
+		if !ok {
+			if !s.park() {
+				return
+			}
+			continue
+		}
+
+		task(w)
+		s.done()
+	}
+}
+
+// Scheduler runs tasks on a fixed pool of workers. each worker keeps the
+// tasks it spawns in its own StealDeque, so most of the work never touches
+// a shared lock, and a worker that runs out steals from the others.
+// tasks submitted from outside the pool go through one shared queue
+type Scheduler struct {
+	workers  []*Worker
+	inject   SynchronizedQueue[Task] // tasks submitted from outside
+	injected atomic.Int64            // tasks in inject, for parked workers to check
+	pending  atomic.Int64            // tasks submitted or spawned and not yet finished
+	closed   atomic.Bool             // no more Submits are accepted once set
+	idle     atomic.Int32            // number of workers parked on cv
+	mtx      sync.Mutex              // only used for parking
+	cv       *sync.Cond              // a condition variable for idle workers
+	wg       sync.WaitGroup          // the running workers
+}
+
+// Submit adds a task from outside the pool
+// if the scheduler is closed, an error is returned
+func (s *Scheduler) Submit(task Task) error {
+	if s.closed.Load() {
+		return ErrClosed
+	}
+
+	s.pending.Add(1)
+	if err := s.inject.Put(task); err != nil {
+		// lost a race with Close
+		s.done()
+		return err
+	}
+	s.injected.Add(1)
+	s.wakeOne()
+
+	return nil
+}
+
+// Close stops accepting Submits, waits until every task, including
+// the ones they spawn, has run, and stops the workers
+func (s *Scheduler) Close() {
+	if s.closed.Swap(true) {
+		s.wg.Wait()
+		return
+	}
+	s.inject.Close()
+	s.wakeAll()
+	s.wg.Wait()
+}
+
+// Workers is the number of workers
+func (s *Scheduler) Workers() int {
+	return len(s.workers)
+}
+
+// Pending is the number of tasks not yet finished
+func (s *Scheduler) Pending() int {
+	return int(s.pending.Load())
+}
+
+// done counts a finished task. the last one after Close
+// lets everyone go home
+func (s *Scheduler) done() {
+	if s.pending.Add(-1) == 0 && s.closed.Load() {
+		s.wakeAll()
+	}
+}
+
+// park waits for work. it returns false when the worker should stop
+func (s *Scheduler) park() bool {
+	// lock the mutex
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	// count ourselves idle before the last look, so a task pushed
+	// after it is sure to see us and signal
+	s.idle.Add(1)
+	defer s.idle.Add(-1)
+
+	for !s.ready() {
+		if s.closed.Load() && s.pending.Load() == 0 {
+			return false
+		}
+		s.cv.Wait()
+	}
+	return true
+}
+
+// ready reports whether any task is waiting to be found.
+// it runs under our mutex, not the one of inject, so it goes by
+// the count of injected tasks rather than inject.Len
+func (s *Scheduler) ready() bool {
+	if s.injected.Load() > 0 {
+		return true
+	}
+	for _, w := range s.workers {
+		if w.deque.Len() > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// wakeOne signals a parked worker, if there is one
+func (s *Scheduler) wakeOne() {
+	if s.idle.Load() > 0 {
+		s.mtx.Lock()
+		s.cv.Signal()
+		s.mtx.Unlock()
+	}
+}
+
+// wakeAll wakes every parked worker
+func (s *Scheduler) wakeAll() {
+	s.mtx.Lock()
+	s.cv.Broadcast()
+	s.mtx.Unlock()
+}
+
+// String
+func (s *Scheduler) String() string {
+	return fmt.Sprintf("Scheduler Workers:%v Pending:%v", s.Workers(), s.Pending())
+}
+
+// NewScheduler starts a scheduler with n workers,
+// or one per usable CPU if n is less than 1
+func NewScheduler(n int) *Scheduler {
+	var s Scheduler
+
+	if n < 1 {
+		n = runtime.GOMAXPROCS(0)
+	}
+
+	s.cv = sync.NewCond(&s.mtx)
+	s.inject = NewSyncGrowableOf[Task](Unbounded)
+
+	s.workers = make([]*Worker, n)
+	for i := range s.workers {
+		s.workers[i] = &Worker{id: i, deque: NewStealDequeOf[Task](), sched: &s, next: i}
+	}
+
+	// start them once they can all see each other
+	s.wg.Add(n)
+	for _, w := range s.workers {
+		go w.run()
+	}
+
+	return &s
+}
@@ -0,0 +1,156 @@
+package queue
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// snapshotTimes says what the times in a snapshot are
+type snapshotTimes byte
+
+const (
+	timesNone    snapshotTimes = iota + 1 // the elements have no times
+	timesDue                              // the time each element is due
+	timesExpires                          // the time each element goes stale
+)
+
+// snapshotQueue is implemented by the queues whose elements
+// have a time, which PeekN doesn't show
+type snapshotQueue[T any] interface {
+	// return all the elements in the order Get would return them
+	// and the time of each one
+	snapshot() ([]T, []time.Time, snapshotTimes)
+}
+
+// restoreQueue is implemented by the queues with a lock,
+// so the room check and the insert are done in one hold of it
+type restoreQueue[T any] interface {
+	// put all the elements back or none of them, with the times
+	// if they are the kind the queue keeps
+	restore(values []T, times []time.Time, kind snapshotTimes) (int, error)
+}
+
+// a snapshot is the magic and the kind of times, the number of
+// elements, then each element as its length and its encoding.
+// with times, each element is followed by its unix nanosecond,
+// 0 for the zero time
+const snapshotMagic = "GOQSNAP"
+
+// Snapshot writes every element of q to w, in the order Get would
+// return them: first in first out, or by priority for a priority queue.
+// the elements are left in the queue.
+//
+// the queues with a lock are captured at one instant. the lock-free
+// ChannelQ and MPMCQueue are captured as far as their elements have
+// been put, so an element whose Put hasn't returned yet may be left out.
+// a DelayQueue captures the elements that aren't due yet too, with
+// the time each one is due, and a SyncTTLQueue captures the time
+// each element goes stale
+func Snapshot[T any](q SynchronizedQueue[T], w io.Writer, codec Codec[T]) error {
+	var values []T
+	var times []time.Time
+	kind := timesNone
+
+	if sq, ok := q.(snapshotQueue[T]); ok {
+		values, times, kind = sq.snapshot()
+	} else {
+		values = q.PeekN(math.MaxInt)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(snapshotMagic)
+	buf.WriteByte(byte(kind))
+	buf.Write(binary.AppendUvarint(nil, uint64(len(values))))
+	for i, value := range values {
+		data, err := codec.Encode(value)
+		if err != nil {
+			return err
+		}
+		buf.Write(binary.AppendUvarint(nil, uint64(len(data))))
+		buf.Write(data)
+		if kind != timesNone {
+			var at int64
+			if !times[i].IsZero() {
+				at = times[i].UnixNano()
+			}
+			buf.Write(binary.AppendVarint(nil, at))
+		}
+	}
+
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// Restore reads a snapshot written by Snapshot from r and puts its
+// elements on q in order, and returns how many it put. the whole
+// snapshot is read first, so nothing is put if it is damaged or there
+// isn't room for all of it. a priority queue gets its elements back
+// with their priorities, a DelayQueue gets them back due at the same
+// time as before, so the ones that came due while the queue was down
+// are due at once, and a SyncTTLQueue gets them back going stale at the
+// same time as before. other queues drop the times.
+//
+// the queues with a lock check for room and put the elements in one
+// hold of it. the lock-free ChannelQ and MPMCQueue check first and then
+// put, so a Put racing the Restore can make it put only some of them
+// and return ErrFull
+func Restore[T any](q SynchronizedQueue[T], r io.Reader, codec Codec[T]) (int, error) {
+	br := bufio.NewReader(r)
+
+	magic := make([]byte, len(snapshotMagic)+1)
+	if _, err := io.ReadFull(br, magic); err != nil || string(magic[:len(snapshotMagic)]) != snapshotMagic {
+		return 0, fmt.Errorf("%w: not a snapshot", ErrCorrupt)
+	}
+	kind := snapshotTimes(magic[len(snapshotMagic)])
+	if kind < timesNone || kind > timesExpires {
+		return 0, fmt.Errorf("%w: not a snapshot", ErrCorrupt)
+	}
+
+	count, err := binary.ReadUvarint(br)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrCorrupt, err)
+	}
+
+	values := make([]T, 0, min(count, 1<<16))
+	var times []time.Time
+	for i := uint64(0); i < count; i++ {
+		length, err := binary.ReadUvarint(br)
+		if err != nil {
+			return 0, fmt.Errorf("%w: element %d: %v", ErrCorrupt, i, err)
+		}
+		data := make([]byte, length)
+		if _, err := io.ReadFull(br, data); err != nil {
+			return 0, fmt.Errorf("%w: element %d: %v", ErrCorrupt, i, err)
+		}
+		value, err := codec.Decode(data)
+		if err != nil {
+			return 0, err
+		}
+		values = append(values, value)
+
+		if kind != timesNone {
+			at, err := binary.ReadVarint(br)
+			if err != nil {
+				return 0, fmt.Errorf("%w: element %d: %v", ErrCorrupt, i, err)
+			}
+			if at == 0 {
+				times = append(times, time.Time{})
+			} else {
+				times = append(times, time.Unix(0, at))
+			}
+		}
+	}
+
+	if rq, ok := q.(restoreQueue[T]); ok {
+		return rq.restore(values, times, kind)
+	}
+	if len(values) > q.Cap()-q.Len() {
+		return 0, ErrFull
+	}
+	return q.PutMany(values)
+}
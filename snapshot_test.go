@@ -0,0 +1,318 @@
+package queue
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+// test variables
+const snqsize int = 8
+
+// a snapshot leaves the queue alone and restores into another
+func snapshot1(t *testing.T, factory func() SynchronizedQueue[int], codec Codec[int]) {
+	var buf bytes.Buffer
+
+	q := factory()
+	for i := 0; i < snqsize-2; i++ {
+		q.Put(i)
+	}
+	// move the head along so a ring has wrapped
+	q.Get()
+	q.Put(snqsize - 2)
+
+	if err := Snapshot(q, &buf, codec); err != nil {
+		t.Error("Snapshot should not fail", q, err)
+	}
+	if q.Len() != snqsize-2 {
+		t.Error("the queue should keep its elements", q, q.Len())
+	}
+
+	r := factory()
+	n, err := Restore(r, &buf, codec)
+	if err != nil || n != snqsize-2 {
+		t.Error("Restore should put them all", r, n, err)
+	}
+	for i := 1; i <= snqsize-2; i++ {
+		v, err := r.TryGet()
+		if err != nil || v != i {
+			t.Error("v should == i", r, v, i, err)
+		}
+	}
+}
+
+// Restore puts nothing if the snapshot is bad or doesn't fit
+func snapshot2(t *testing.T) {
+	var buf bytes.Buffer
+
+	q := NewSyncCircularOf[int](snqsize)
+	for i := 0; i < snqsize; i++ {
+		q.Put(i)
+	}
+	Snapshot(q, &buf, GobCodec[int]{})
+	data := buf.Bytes()
+
+	r := NewSyncCircularOf[int](snqsize)
+	r.Put(99)
+	if n, err := Restore(r, bytes.NewReader(data), GobCodec[int]{}); !errors.Is(err, ErrFull) || n != 0 {
+		t.Error("err should be ErrFull", n, err)
+	}
+	if r.Len() != 1 {
+		t.Error("length should == 1", r.Len())
+	}
+
+	r = NewSyncCircularOf[int](snqsize)
+	if _, err := Restore(r, bytes.NewReader(data[:len(data)-1]), GobCodec[int]{}); !errors.Is(err, ErrCorrupt) {
+		t.Error("err should be ErrCorrupt", err)
+	}
+	if _, err := Restore(r, bytes.NewReader([]byte("not a snapshot")), GobCodec[int]{}); !errors.Is(err, ErrCorrupt) {
+		t.Error("err should be ErrCorrupt", err)
+	}
+	if r.Len() != 0 {
+		t.Error("length should == 0", r.Len())
+	}
+}
+
+// a priority queue comes back in priority order
+func snapshot3(t *testing.T, codec Codec[PriorityItem[string]]) {
+	var buf bytes.Buffer
+
+	q := NewSyncPriorityOf[string](snqsize)
+	q.PutPriority("c", 3)
+	q.PutPriority("a", 1)
+	q.PutPriority("b1", 2)
+	q.PutPriority("b2", 2)
+
+	if err := Snapshot[PriorityItem[string]](q, &buf, codec); err != nil {
+		t.Error("Snapshot should not fail", err)
+	}
+
+	r := NewSyncPriorityOf[string](snqsize)
+	if _, err := Restore[PriorityItem[string]](r, &buf, codec); err != nil {
+		t.Error("Restore should not fail", err)
+	}
+	for _, want := range []struct {
+		value    string
+		priority int
+	}{{"a", 1}, {"b1", 2}, {"b2", 2}, {"c", 3}} {
+		v, p, err := r.TryGetPriority()
+		if err != nil || v != want.value || p != want.priority {
+			t.Error("the items should come back in order", v, p, want, err)
+		}
+	}
+}
+
+// a snapshot taken during Puts and Gets holds a run of what was put
+func snapshot4(t *testing.T, q SynchronizedQueue[int]) {
+	var wg sync.WaitGroup
+
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 2000; i++ {
+			q.Put(i)
+		}
+		q.Close()
+	}()
+	go func() {
+		defer wg.Done()
+		for {
+			if _, err := q.Get(); err != nil {
+				return
+			}
+		}
+	}()
+
+	for i := 0; i < 100; i++ {
+		var buf bytes.Buffer
+		if err := Snapshot(q, &buf, GobCodec[int]{}); err != nil {
+			t.Error("Snapshot should not fail", q, err)
+		}
+		r := NewSyncGrowableOf[int](Unbounded)
+		Restore(r, &buf, GobCodec[int]{})
+		values := r.PeekN(math.MaxInt)
+		for j := 1; j < len(values); j++ {
+			if values[j] != values[j-1]+1 {
+				t.Error("the snapshot should be consecutive", q, values)
+				break
+			}
+		}
+		runtime.Gosched()
+	}
+	wg.Wait()
+}
+
+// a Restore racing Puts puts all of the snapshot or none of it
+func snapshot5(t *testing.T, factory func() SynchronizedQueue[int]) {
+	var buf bytes.Buffer
+
+	src := NewSyncCircularOf[int](snqsize)
+	for i := 0; i < snqsize/2; i++ {
+		src.Put(i)
+	}
+	Snapshot[int](src, &buf, GobCodec[int]{})
+	data := buf.Bytes()
+
+	for i := 0; i < 100; i++ {
+		q := factory()
+		q.Put(-1)
+
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for q.TryPut(-1) == nil {
+				runtime.Gosched()
+			}
+		}()
+
+		n, err := Restore(q, bytes.NewReader(data), GobCodec[int]{})
+		if (n != 0 || !errors.Is(err, ErrFull)) && (n != snqsize/2 || err != nil) {
+			t.Error("Restore should put all or none", q, n, err)
+		}
+		wg.Wait()
+	}
+}
+
+// SNAPSHOT AND RESTORE
+func TestSnapshot(t *testing.T) {
+	factories := []func() SynchronizedQueue[int]{
+		func() SynchronizedQueue[int] { return NewChannelQueueOf[int](snqsize) },
+		func() SynchronizedQueue[int] { return NewSyncCircularOf[int](snqsize) },
+		func() SynchronizedQueue[int] { return NewSyncListOf[int](snqsize) },
+		func() SynchronizedQueue[int] { return NewSyncRingOf[int](snqsize) },
+		func() SynchronizedQueue[int] { return NewSyncSliceOf[int](snqsize) },
+		func() SynchronizedQueue[int] { return NewSyncGrowableOf[int](snqsize) },
+		func() SynchronizedQueue[int] { return NewNativeQueueOf[int](snqsize) },
+		func() SynchronizedQueue[int] { return NewMPMCQueueOf[int](snqsize) },
+		func() SynchronizedQueue[int] { return NewDequeOf[int](snqsize) },
+		func() SynchronizedQueue[int] { return NewDelayQueueOf[int](snqsize) },
+	}
+	for _, factory := range factories {
+		snapshot1(t, factory, GobCodec[int]{})
+		snapshot1(t, factory, JSONCodec[int]{})
+	}
+
+	snapshot2(t)
+	snapshot4(t, NewChannelQueueOf[int](snqsize))
+	snapshot4(t, NewMPMCQueueOf[int](snqsize))
+	snapshot4(t, NewSyncCircularOf[int](snqsize))
+	snapshot3(t, GobCodec[PriorityItem[string]]{})
+	snapshot3(t, JSONCodec[PriorityItem[string]]{})
+	for _, factory := range factories[1:] {
+		if _, ok := factory().(restoreQueue[int]); ok {
+			snapshot5(t, factory)
+		}
+	}
+}
+
+// elements that aren't due yet are captured too, with their times
+func TestSnapshotDelay(t *testing.T) {
+	var buf bytes.Buffer
+
+	q := NewDelayQueueOf[int](snqsize)
+	q.PutAfter(2, time.Hour)
+	q.PutAfter(1, time.Minute)
+	q.Put(0)
+	Snapshot[int](q, &buf, GobCodec[int]{})
+
+	r := NewDelayQueueOf[int](snqsize)
+	if n, err := Restore[int](r, &buf, GobCodec[int]{}); err != nil || n != 3 {
+		t.Error("Restore should put them all", n, err)
+	}
+
+	// only the one that was due already is due
+	if v, err := r.TryGet(); err != nil || v != 0 {
+		t.Error("v should == 0", v, err)
+	}
+	if _, err := r.TryGet(); !errors.Is(err, ErrEmpty) {
+		t.Error("err should be ErrEmpty", err)
+	}
+	if r.Len() != 2 {
+		t.Error("length should == 2", r.Len())
+	}
+
+	// and the others are due when they were before
+	items := r.queue.PeekN(2)
+	for i, after := range []time.Duration{time.Minute, time.Hour} {
+		if wait := time.Until(items[i].at); wait <= after-time.Second || wait > after {
+			t.Error("the due time should be kept", items[i].value, wait)
+		}
+	}
+
+	// a queue without times just gets them in order
+	buf.Reset()
+	Snapshot[int](r, &buf, GobCodec[int]{})
+	c := NewSyncCircularOf[int](snqsize)
+	Restore(c, &buf, GobCodec[int]{})
+	for i := 1; i <= 2; i++ {
+		if v, err := c.TryGet(); err != nil || v != i {
+			t.Error("v should == i", v, i, err)
+		}
+	}
+}
+
+// elements of a SyncTTLQueue go stale when they did before
+func TestSnapshotTTL(t *testing.T) {
+	var buf bytes.Buffer
+
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	options := TTLOptions[int]{TTL: time.Hour, Now: clock.Now}
+
+	q := NewSyncTTLOf(NewCircularQueueOf[TTLItem[int]](snqsize), options)
+	q.PutTTL(0, time.Second)
+	q.PutTTL(1, 0)
+	q.PutTTL(2, time.Minute)
+	q.PutTTL(3, 2*time.Minute)
+	clock.now = clock.now.Add(2 * time.Second)
+	Snapshot[int](q, &buf, GobCodec[int]{})
+
+	// the stale one is left out
+	r := NewSyncTTLOf(NewCircularQueueOf[TTLItem[int]](snqsize), options)
+	if n, err := Restore[int](r, &buf, GobCodec[int]{}); err != nil || n != 3 {
+		t.Error("Restore should put the live ones", n, err)
+	}
+
+	// and the rest keep their times
+	clock.now = clock.now.Add(90 * time.Second)
+	for _, want := range []int{1, 3} {
+		if v, err := r.TryGet(); err != nil || v != want {
+			t.Error("v should == want", v, want, err)
+		}
+	}
+	if r.Expired() != 1 {
+		t.Error("2 should have gone stale", r.Expired())
+	}
+
+	// a queue without times gets them the default time to live
+	q = NewSyncTTLOf(NewCircularQueueOf[TTLItem[int]](snqsize), options)
+	q.PutTTL(0, time.Second)
+	buf.Reset()
+	Snapshot[int](q, &buf, GobCodec[int]{})
+	d := NewDelayQueueOf[int](snqsize)
+	if n, err := Restore[int](d, &buf, GobCodec[int]{}); err != nil || n != 1 {
+		t.Error("Restore should put it", n, err)
+	}
+	if v, err := d.TryGet(); err != nil || v != 0 {
+		t.Error("a DelayQueue should not use the time it goes stale", v, err)
+	}
+
+	buf.Reset()
+	c := NewSyncCircularOf[int](snqsize)
+	c.Put(5)
+	c.Put(6)
+	Snapshot[int](c, &buf, GobCodec[int]{})
+	Restore[int](r, &buf, GobCodec[int]{})
+	clock.now = clock.now.Add(time.Minute)
+	if v, err := r.TryGet(); err != nil || v != 5 {
+		t.Error("v should == 5", v, err)
+	}
+	clock.now = clock.now.Add(time.Hour)
+	if _, err := r.TryGet(); !errors.Is(err, ErrEmpty) || r.Expired() != 2 {
+		t.Error("6 should have gone stale", r.Expired(), err)
+	}
+}
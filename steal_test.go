@@ -0,0 +1,215 @@
+package queue
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// test variables
+const stsize int = 1000
+
+// the owner works the bottom like a stack, thieves take from the top
+func steal1(t *testing.T, d *StealDeque[int]) {
+	if _, err := d.Pop(); !errors.Is(err, ErrEmpty) {
+		t.Error("err should be ErrEmpty", err)
+	}
+	if _, err := d.Steal(); !errors.Is(err, ErrEmpty) {
+		t.Error("err should be ErrEmpty", err)
+	}
+
+	// more than the first ring holds
+	for i := 0; i < stsize; i++ {
+		d.Push(i)
+	}
+	if d.Len() != stsize {
+		t.Error("length should == stsize", d.Len())
+	}
+
+	for i := 0; i < stsize/2; i++ {
+		v, err := d.Steal()
+		if err != nil || v != i {
+			t.Error("Steal should return i", v, i, err)
+		}
+		v, err = d.Pop()
+		if err != nil || v != stsize-1-i {
+			t.Error("Pop should return stsize-1-i", v, stsize-1-i, err)
+		}
+	}
+	if d.Len() != 0 {
+		t.Error("length should == 0", d.Len())
+	}
+}
+
+// every element is taken exactly once by the owner or a thief
+func steal2(t *testing.T, d *StealDeque[int], thieves int) {
+	var wg sync.WaitGroup
+	var stop atomic.Bool
+	seen := make([]atomic.Int32, stsize*10)
+
+	for i := 0; i < thieves; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				v, err := d.Steal()
+				if err == nil {
+					seen[v].Add(1)
+				} else if stop.Load() {
+					return
+				}
+			}
+		}()
+	}
+
+	// push in bursts and pop some back, racing for the last ones
+	for i := 0; i < len(seen); {
+		for j := 0; j < 7 && i < len(seen); j++ {
+			d.Push(i)
+			i++
+		}
+		for j := 0; j < 5; j++ {
+			if v, err := d.Pop(); err == nil {
+				seen[v].Add(1)
+			}
+		}
+	}
+	for {
+		v, err := d.Pop()
+		if err != nil {
+			break
+		}
+		seen[v].Add(1)
+	}
+	stop.Store(true)
+	wg.Wait()
+
+	for i := range seen {
+		if seen[i].Load() != 1 {
+			t.Error("each element should be taken once", i, seen[i].Load())
+		}
+	}
+}
+
+// tasks spawned by tasks all run before Close returns
+func sched1(t *testing.T, s *Scheduler) {
+	var count atomic.Int64
+
+	// a binary tree of tasks 10 deep, 2047 in all
+	var tree func(depth int) Task
+	tree = func(depth int) Task {
+		return func(w *Worker) {
+			count.Add(1)
+			if depth > 0 {
+				w.Spawn(tree(depth - 1))
+				w.Spawn(tree(depth - 1))
+			}
+		}
+	}
+	for i := 0; i < 4; i++ {
+		if err := s.Submit(tree(10)); err != nil {
+			t.Error("Submit should not fail", err)
+		}
+	}
+
+	s.Close()
+	if count.Load() != 4*2047 {
+		t.Error("count should == 4*2047", count.Load())
+	}
+	if s.Pending() != 0 {
+		t.Error("pending should == 0", s.Pending())
+	}
+	if err := s.Submit(func(w *Worker) {}); !errors.Is(err, ErrClosed) {
+		t.Error("err should be ErrClosed", err)
+	}
+	// a second Close is harmless
+	s.Close()
+}
+
+// idle workers steal from a busy one
+func sched2(t *testing.T, s *Scheduler) {
+	var mtx sync.Mutex
+	ran := make(map[int]bool)
+	release := make(chan struct{})
+
+	s.Submit(func(w *Worker) {
+		for i := 0; i < 64; i++ {
+			w.Spawn(func(w *Worker) {
+				mtx.Lock()
+				ran[w.ID()] = true
+				mtx.Unlock()
+				<-release
+			})
+		}
+		// hold on to this worker until the others have stolen
+		for {
+			mtx.Lock()
+			n := len(ran)
+			mtx.Unlock()
+			if n > 0 {
+				break
+			}
+		}
+		close(release)
+	})
+
+	s.Close()
+	if len(ran) == 0 {
+		t.Error("other workers should have stolen", ran)
+	}
+}
+
+// several goroutines submit while the workers keep parking
+func sched3(t *testing.T, s *Scheduler, submitters int) {
+	const count = 200
+	var ran atomic.Int64
+	var wg sync.WaitGroup
+
+	for i := 0; i < submitters; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < count; j++ {
+				if err := s.Submit(func(w *Worker) { ran.Add(1) }); err != nil {
+					t.Error("Submit should not fail", err)
+				}
+				// give the workers time to run out and park
+				if j%10 == 0 {
+					time.Sleep(time.Millisecond)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	s.Close()
+	if ran.Load() != int64(submitters*count) {
+		t.Error("every submitted task should run", ran.Load(), submitters*count)
+	}
+}
+
+// WORK STEALING
+func TestSteal(t *testing.T) {
+	steal1(t, NewStealDequeOf[int]())
+	steal2(t, NewStealDequeOf[int](), 1)
+	steal2(t, NewStealDequeOf[int](), 4)
+}
+
+func TestScheduler(t *testing.T) {
+	sched1(t, NewScheduler(4))
+	sched1(t, NewScheduler(1))
+	sched2(t, NewScheduler(4))
+	sched3(t, NewScheduler(4), 8)
+
+	// no workers means one per CPU
+	for _, n := range []int{0, -1} {
+		s := NewScheduler(n)
+		if s.Workers() != runtime.GOMAXPROCS(0) {
+			t.Error("workers should == GOMAXPROCS", n, s.Workers())
+		}
+		sched1(t, s)
+	}
+}
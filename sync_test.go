@@ -1,6 +1,7 @@
 package queue
 
 import (
+	"errors"
 	"testing"
 )
 
@@ -8,7 +9,7 @@ import (
 const sqsize int = 8
 
 // test an instance of a SynchronizedQueue
-func sync1(t *testing.T, q SynchronizedQueue) {
+func sync1(t *testing.T, q SynchronizedQueue[interface{}]) {
 	var err error
 
 	if q == nil {
@@ -17,20 +18,20 @@ func sync1(t *testing.T, q SynchronizedQueue) {
 
 	// check length
 	if q.Len() != 0 {
-		t.Error("length should be 0",q.Len())
+		t.Error("length should be 0", q.Len())
 	}
 
 	// check capacity
 	if q.Cap() != sqsize {
-		t.Error("capacity should == sqsize",q.Cap(),sqsize)
+		t.Error("capacity should == sqsize", q.Cap(), sqsize)
 	}
 
 	// fill the queue with ints
-	for i:=0;i<q.Cap();i++ {
+	for i := 0; i < q.Cap(); i++ {
 		q.TryPut(i)
 		//length should be == i at this point
-		if q.Len() != (i+1) {
-			t.Error("length should == i+1",q.Len(),i+1)
+		if q.Len() != (i + 1) {
+			t.Error("length should == i+1", q.Len(), i+1)
 		}
 	}
 
@@ -41,23 +42,22 @@ func sync1(t *testing.T, q SynchronizedQueue) {
 
 	// try to add one more
 	err = q.TryPut(99)
-	if err == nil {
-		t.Error("err should be nil")
+	if !errors.Is(err, ErrFull) {
+		t.Error("err should be ErrFull", err)
 	}
 	// check length is unchanged
 	if q.Len() != sqsize {
 		t.Error("length should == sqsize")
 	}
 
-	// cleanup 
-	// for channels, this closes it for further Puts
+	// cleanup
+	// this closes it for further Puts
 	// any remaing data is still available for Gets
-	// it is a noop for the mutex/condition variable methods
 	q.Close()
 
 	// remove all items. no need to block
 	j := q.Len() - 1
-	for i:=0;i<q.Cap();i++ {
+	for i := 0; i < q.Cap(); i++ {
 		value, err := q.TryGet()
 		if err != nil {
 			t.Error(err)
@@ -65,22 +65,21 @@ func sync1(t *testing.T, q SynchronizedQueue) {
 		// convert to int
 		v := value.(int)
 		if v != i {
-			t.Error("v should == i",v,i)
+			t.Error("v should == i", v, i)
 		}
 		// length should decrease
 		if q.Len() != j {
-			t.Error("length should == i",q.Len(),j)
+			t.Error("length should == i", q.Len(), j)
 		}
 		j--
 	}
 
 	// check the length == 0
 	if q.Len() != 0 {
-		t.Error("length should == 0",q.Len())
+		t.Error("length should == 0", q.Len())
 	}
 }
 
-
 // test an instance of a SynchronizedQueue
 func sync2(t *testing.T, q *NativeIntQueue) {
 	var err error
@@ -91,20 +90,20 @@ func sync2(t *testing.T, q *NativeIntQueue) {
 
 	// check length
 	if q.Len() != 0 {
-		t.Error("length should be 0",q.Len())
+		t.Error("length should be 0", q.Len())
 	}
 
 	// check capacity
 	if q.Cap() != sqsize {
-		t.Error("capacity should == sqsize",q.Cap(),sqsize)
+		t.Error("capacity should == sqsize", q.Cap(), sqsize)
 	}
 
 	// fill the queue with ints
-	for i:=0;i<q.Cap();i++ {
+	for i := 0; i < q.Cap(); i++ {
 		q.Put(i)
 		//length should be == i at this point
-		if q.Len() != (i+1) {
-			t.Error("length should == i+1",q.Len(),i+1)
+		if q.Len() != (i + 1) {
+			t.Error("length should == i+1", q.Len(), i+1)
 		}
 	}
 
@@ -115,40 +114,218 @@ func sync2(t *testing.T, q *NativeIntQueue) {
 
 	// try to add one more
 	err = q.TryPut(99)
-	if err == nil {
-		t.Error("err should be nil")
+	if !errors.Is(err, ErrFull) {
+		t.Error("err should be ErrFull", err)
 	}
 	// check length is unchanged
 	if q.Len() != sqsize {
 		t.Error("length should == sqsize")
 	}
 
-	// cleanup 
-	// for channels, this closes it for further Puts
+	// cleanup
+	// this closes it for further Puts
 	// any remaing data is still available for Gets
-	// it is a noop for the mutex/condition variable methods
 	q.Close()
 
 	// remove all items
 	j := q.Len() - 1
-	for i:=0;i<q.Cap();i++ {
+	for i := 0; i < q.Cap(); i++ {
 		v, err := q.TryGet()
 		if err != nil {
 			t.Error(err)
 		}
 		if v != i {
-			t.Error("v should == i",v,i)
+			t.Error("v should == i", v, i)
 		}
 		// length should decrease
 		if q.Len() != j {
-			t.Error("length should == i",q.Len(),j)
+			t.Error("length should == i", q.Len(), j)
 		}
 		j--
 	}
 
 	// check the length == 0
 	if q.Len() != 0 {
-		t.Error("length should == 0",q.Len())
+		t.Error("length should == 0", q.Len())
+	}
+}
+
+// test a single-producer single-consumer queue
+func sync4(t *testing.T, q *SPSCQueue[int]) {
+	var err error
+
+	if q == nil {
+		t.Error("q should not be nil")
+	}
+
+	// check capacity
+	if q.Cap() != sqsize {
+		t.Error("capacity should == sqsize", q.Cap(), sqsize)
+	}
+
+	// go around the ring a few times
+	for k := 0; k < 3; k++ {
+		// fill the queue with ints
+		for i := 0; i < q.Cap(); i++ {
+			q.Put(i)
+			//length should be == i at this point
+			if q.Len() != (i + 1) {
+				t.Error("length should == i+1", q.Len(), i+1)
+			}
+		}
+
+		// try to add one more
+		err = q.TryPut(99)
+		if !errors.Is(err, ErrFull) {
+			t.Error("err should be ErrFull", err)
+		}
+
+		// remove all items
+		for i := 0; i < q.Cap(); i++ {
+			v, err := q.TryGet()
+			if err != nil || v != i {
+				t.Error("v should == i", v, i, err)
+			}
+		}
+
+		_, err = q.TryGet()
+		if !errors.Is(err, ErrEmpty) {
+			t.Error("err should be ErrEmpty", err)
+		}
+	}
+
+	// a closed queue is drained and then reports ErrClosed
+	q.Put(1)
+	q.Close()
+	if err = q.TryPut(2); !errors.Is(err, ErrClosed) {
+		t.Error("err should be ErrClosed", err)
+	}
+	if v, err := q.Get(); err != nil || v != 1 {
+		t.Error("Get should return 1", v, err)
+	}
+	if _, err = q.Get(); !errors.Is(err, ErrClosed) {
+		t.Error("err should be ErrClosed", err)
+	}
+}
+
+// test an instance of a typed SynchronizedQueue
+// no type assertions are needed on the way out
+func sync3(t *testing.T, q SynchronizedQueue[int]) {
+	if q == nil {
+		t.Error("q should not be nil")
+	}
+
+	// check capacity
+	if q.Cap() != sqsize {
+		t.Error("capacity should == sqsize", q.Cap(), sqsize)
+	}
+
+	// fill the queue with ints
+	for i := 0; i < q.Cap(); i++ {
+		err := q.TryPut(i)
+		if err != nil {
+			t.Error(err)
+		}
+	}
+
+	// try to add one more
+	err := q.TryPut(99)
+	if !errors.Is(err, ErrFull) {
+		t.Error("err should be ErrFull", err)
+	}
+
+	// remove all items
+	for i := 0; i < q.Cap(); i++ {
+		v, err := q.TryGet()
+		if err != nil {
+			t.Error(err)
+		}
+		if v != i {
+			t.Error("v should == i", v, i)
+		}
+	}
+
+	// check the length == 0
+	if q.Len() != 0 {
+		t.Error("length should == 0", q.Len())
+	}
+
+	// nothing left
+	_, err = q.TryGet()
+	if !errors.Is(err, ErrEmpty) {
+		t.Error("err should be ErrEmpty", err)
+	}
+}
+
+// test an instance of a plain Queue backend
+func queue1(t *testing.T, q Queue[int]) {
+	_, err := q.Pop()
+	if !errors.Is(err, ErrEmpty) {
+		t.Error("err should be ErrEmpty", err)
+	}
+
+	for i := 0; i < q.Cap(); i++ {
+		err = q.Push(i)
+		if err != nil {
+			t.Error(err)
+		}
+	}
+
+	err = q.Push(99)
+	if !errors.Is(err, ErrFull) {
+		t.Error("err should be ErrFull", err)
+	}
+
+	for i := 0; i < q.Cap(); i++ {
+		v, err := q.Pop()
+		if err != nil {
+			t.Error(err)
+		}
+		if v != i {
+			t.Error("v should == i", v, i)
+		}
+	}
+}
+
+// test an unbounded growable queue
+func growable1(t *testing.T, q *GrowableQueue[int]) {
+	if q.Cap() != Unbounded {
+		t.Error("capacity should == Unbounded", q.Cap())
+	}
+
+	// a burst grows the buffer, Push never fails
+	for i := 0; i < 1000; i++ {
+		if err := q.Push(i); err != nil {
+			t.Error(err)
+		}
+	}
+	if len(q.queue) < 1000 {
+		t.Error("buffer should have grown", len(q.queue))
+	}
+
+	// pop half, then wrap the buffer while pushing more
+	for i := 0; i < 500; i++ {
+		v, err := q.Pop()
+		if err != nil || v != i {
+			t.Error("v should == i", v, i, err)
+		}
+	}
+	q.PushMany([]int{1000, 1001, 1002})
+
+	// everything comes out in order
+	for i := 500; i < 1003; i++ {
+		v, err := q.Pop()
+		if err != nil || v != i {
+			t.Error("v should == i", v, i, err)
+		}
+	}
+
+	// the memory is given back once the burst is drained
+	if len(q.queue) != growableMinSize {
+		t.Error("buffer should have shrunk", len(q.queue))
+	}
+	if _, err := q.Pop(); !errors.Is(err, ErrEmpty) {
+		t.Error("err should be ErrEmpty", err)
 	}
 }
 
@@ -159,43 +336,131 @@ func sync2(t *testing.T, q *NativeIntQueue) {
 // CHANNEL
 func TestChannelSync(t *testing.T) {
 	// using channel
-	sync1(t,NewChannelQueue(sqsize))
+	sync1(t, NewChannelQueue(sqsize))
 }
 
 // NATIVE QUEUE
 func TestQueueNativeSync(t *testing.T) {
 	// using condition variable queue
-	sync2(t,NewNativeQueue(sqsize))
+	sync2(t, NewNativeQueue(sqsize))
 }
 
+// SPSC QUEUE
+func TestSPSCSync(t *testing.T) {
+	// using the lock-free ring
+	sync4(t, NewSPSCQueueOf[int](sqsize))
+
+	// the capacity is rounded up to a power of two
+	q := NewSPSCQueueOf[int](sqsize - 1)
+	if q.Cap() != sqsize {
+		t.Error("capacity should == sqsize", q.Cap(), sqsize)
+	}
+}
+
+// MPMC QUEUE
+func TestMPMCSync(t *testing.T) {
+	// using the lock-free slots
+	sync1(t, NewMPMCQueue(sqsize))
+}
 
 // CIRCULAR BUFFER using SynchronizedQueue wrapper
 func TestCircularQueueSync(t *testing.T) {
 	// using condition variable queue
-	sync1(t,NewSyncCircular(sqsize))
+	sync1(t, NewSyncCircular(sqsize))
 }
 
 // LIST QUEUE using SynchronizedQueue wrapper
 func TestListSync(t *testing.T) {
 	// using condition variable queue
-	sync1(t,NewSyncList(sqsize))
+	sync1(t, NewSyncList(sqsize))
 }
 
 // RING QUEUE using SynchronizedQueue wrapper
 func TestRingSync(t *testing.T) {
 	// using condition variable queue
-	sync1(t,NewSyncRing(sqsize))
+	sync1(t, NewSyncRing(sqsize))
 }
 
 // SLICE QUEUE using SynchronizedQueue wrapper
 func TestSliceSync(t *testing.T) {
 	// using condition variable queue
-	sync1(t,NewSyncSlice(sqsize))
+	sync1(t, NewSyncSlice(sqsize))
+}
+
+// GROWABLE QUEUE using SynchronizedQueue wrapper
+func TestGrowableSync(t *testing.T) {
+	// using a soft bound
+	sync1(t, NewSyncGrowable(sqsize))
+
+	// without a bound
+	growable1(t, NewGrowableQueueOf[int](Unbounded).(*GrowableQueue[int]))
+
+	// Puts never block
+	q := NewSyncGrowableOf[int](Unbounded)
+	for i := 0; i < 1000; i++ {
+		if err := q.Put(i); err != nil {
+			t.Error(err)
+		}
+	}
+	if q.Len() != 1000 {
+		t.Error("length should == 1000", q.Len())
+	}
+}
+
+// TYPED QUEUES using the generic factories
+func TestTypedSync(t *testing.T) {
+	sync3(t, NewChannelQueueOf[int](sqsize))
+	sync3(t, NewSyncCircularOf[int](sqsize))
+	sync3(t, NewSyncListOf[int](sqsize))
+	sync3(t, NewSyncRingOf[int](sqsize))
+	sync3(t, NewSyncSliceOf[int](sqsize))
+	sync3(t, NewNativeQueueOf[int](sqsize))
+	sync3(t, NewMPMCQueueOf[int](sqsize))
+	sync3(t, NewSyncGrowableOf[int](sqsize))
+}
+
+// QUEUE BACKENDS return the sentinel errors
+func TestQueueErrorsSync(t *testing.T) {
+	queue1(t, NewCircularQueueOf[int](sqsize))
+	queue1(t, NewListQueueOf[int](sqsize))
+	queue1(t, NewRingQueueOf[int](sqsize))
+	queue1(t, NewSliceQueueOf[int](sqsize))
+	queue1(t, NewGrowableQueueOf[int](sqsize))
+}
+
+// brokenQueue claims to hold elements but fails every Pop
+type brokenQueue struct {
+	CircularQueue[int]
+}
+
+var errBroken = errors.New("broken backend")
+
+func (bq *brokenQueue) Pop() (int, error) {
+	return 0, errBroken
+}
+
+// BACKEND ERRORS are returned instead of exiting the program
+func TestBackendErrorSync(t *testing.T) {
+	var bq brokenQueue
+	bq.capacity = sqsize
+	bq.queue = make([]int, sqsize)
+
+	q := NewSynchronizedQueueOf[int](&bq)
+	q.Put(1)
+
+	_, err := q.Get()
+	if !errors.Is(err, errBroken) {
+		t.Error("Get err should be errBroken", err)
+	}
+	_, err = q.TryGet()
+	if !errors.Is(err, errBroken) {
+		t.Error("TryGet err should be errBroken", err)
+	}
 }
 
 // Strings
 func TestStringsSync(t *testing.T) {
-	var q SynchronizedQueue
+	var q SynchronizedQueue[interface{}]
 	q = NewChannelQueue(sqsize)
 	q.Put(1)
 	t.Log(q.String())
@@ -213,13 +478,17 @@ func TestStringsSync(t *testing.T) {
 	t.Log(q.String())
 
 	q = NewSyncPriority(sqsize)
-	q.Put(PriorityItem{1,1})
+	q.Put(NewPriorityItem[interface{}](1, 1))
 	t.Log(q.String())
 
 	q = NewSyncSlice(sqsize)
 	q.Put(1)
 	t.Log(q.String())
 
+	q = NewSyncGrowable(Unbounded)
+	q.Put(1)
+	t.Log(q.String())
+
 	// native queue isn't wrapped
 	nq := NewNativeQueue(sqsize)
 	nq.Put(1)
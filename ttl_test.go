@@ -0,0 +1,145 @@
+package queue
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// test variables
+const tlqsize int = 8
+
+// stale elements are skipped and counted
+func ttl1(t *testing.T, newq func(options TTLOptions[int]) *SyncTTLQueue[int]) {
+	var expired []int
+
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	dead := NewSyncCircularOf[int](tlqsize)
+	q := newq(TTLOptions[int]{
+		TTL:      time.Minute,
+		OnExpire: func(v int) { expired = append(expired, v) },
+		Expired:  dead,
+		Now:      clock.Now,
+	})
+
+	q.Put(0)                   // the default, a minute
+	q.PutTTL(1, time.Second)   // goes stale first
+	q.TryPutTTL(2, 0)          // never goes stale
+	q.PutTTL(3, 2*time.Second) // goes stale second
+
+	// nothing is stale yet
+	values := q.PeekN(10)
+	if len(values) != 4 {
+		t.Error("PeekN should return 4 values", values)
+	}
+
+	clock.now = clock.now.Add(90 * time.Second)
+	values = q.PeekN(10)
+	if len(values) != 1 || values[0] != 2 {
+		t.Error("PeekN should return [2]", values)
+	}
+
+	v, err := q.Get()
+	if err != nil || v != 2 {
+		t.Error("Get should return 2", v, err)
+	}
+
+	// the last one is dropped and the queue is empty
+	_, err = q.TryGet()
+	if !errors.Is(err, ErrEmpty) {
+		t.Error("err should be ErrEmpty", err)
+	}
+	if q.Len() != 0 {
+		t.Error("length should == 0", q.Len())
+	}
+
+	if q.Expired() != 3 || len(expired) != 3 || dead.Len() != 3 {
+		t.Error("3 elements should have expired", q.Expired(), expired, dead.Len())
+	}
+	for _, want := range []int{0, 1, 3} {
+		v, _ := dead.TryGet()
+		if v != want {
+			t.Error("expired queue should hold want", v, want)
+		}
+	}
+}
+
+// a Get blocked on a queue of stale elements keeps waiting
+// and a Put blocked on it is woken when they are dropped
+func ttl2(t *testing.T, q *SyncTTLQueue[int], clock *fakeClock) {
+	var wg sync.WaitGroup
+
+	for i := 0; i < q.Cap(); i++ {
+		q.PutTTL(i, time.Second)
+	}
+	clock.now = clock.now.Add(time.Minute)
+
+	// the queue is full of stale elements, the Put blocks
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := q.Put(99); err != nil {
+			t.Error(err)
+		}
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	// the Get drops them all and frees the Put, which gives it an element
+	v, err := q.GetTimeout(time.Second)
+	if err != nil || v != 99 {
+		t.Error("Get should return 99", v, err)
+	}
+	wg.Wait()
+
+	if q.Expired() != q.Cap() {
+		t.Error("all elements should have expired", q.Expired())
+	}
+}
+
+// a negative time to live is rejected
+func ttl3(t *testing.T, q *SyncTTLQueue[int]) {
+	if err := q.PutTTL(1, -time.Second); err == nil {
+		t.Error("PutTTL should reject a negative ttl", q)
+	}
+	if err := q.TryPutTTL(2, -time.Second); err == nil {
+		t.Error("TryPutTTL should reject a negative ttl", q)
+	}
+	if q.Len() != 0 {
+		t.Error("length should == 0", q.Len())
+	}
+
+	// a negative default too
+	q = NewSyncTTLOf(NewCircularQueueOf[TTLItem[int]](tlqsize), TTLOptions[int]{TTL: -time.Minute})
+	if err := q.Put(3); err == nil {
+		t.Error("Put should reject a negative default ttl", q)
+	}
+	if err := q.PutTTL(4, 0); err != nil || q.Len() != 1 {
+		t.Error("PutTTL should still take a valid ttl", q.Len(), err)
+	}
+}
+
+// TTL QUEUE over several backends
+func TestTTL(t *testing.T) {
+	ttl1(t, func(options TTLOptions[int]) *SyncTTLQueue[int] {
+		return NewSyncTTLOf(NewCircularQueueOf[TTLItem[int]](tlqsize), options)
+	})
+	ttl1(t, func(options TTLOptions[int]) *SyncTTLQueue[int] {
+		return NewSyncTTLOf(NewListQueueOf[TTLItem[int]](tlqsize), options)
+	})
+
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	ttl2(t, NewSyncTTLOf(NewSliceQueueOf[TTLItem[int]](tlqsize), TTLOptions[int]{Now: clock.Now}), clock)
+	ttl3(t, NewSyncTTLOf(NewCircularQueueOf[TTLItem[int]](tlqsize), TTLOptions[int]{}))
+}
+
+// without a TTL it is an ordinary SynchronizedQueue
+func TestTTLSync(t *testing.T) {
+	sync3(t, NewSyncTTLOf(NewCircularQueueOf[TTLItem[int]](sqsize), TTLOptions[int]{}))
+	closeAll(t, func() SynchronizedQueue[int] {
+		return NewSyncTTLOf(NewCircularQueueOf[TTLItem[int]](clqsize), TTLOptions[int]{TTL: time.Hour})
+	})
+	peekAll(t, func() SynchronizedQueue[int] {
+		return NewSyncTTLOf(NewCircularQueueOf[TTLItem[int]](pkqsize), TTLOptions[int]{TTL: time.Hour})
+	})
+}
@@ -0,0 +1,356 @@
+package queue
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrCorrupt is returned when a persistent queue finds
+// a damaged record anywhere but at the end of its log
+var ErrCorrupt = errors.New("queue log is corrupt")
+
+// FsyncPolicy says when a persistent queue flushes its log to disk
+type FsyncPolicy int
+
+const (
+	// FsyncAlways flushes after every record. nothing acknowledged
+	// is lost in a crash. the default
+	FsyncAlways FsyncPolicy = iota
+	// FsyncInterval flushes at most once per interval, a crash
+	// loses at most the last interval of records
+	FsyncInterval
+	// FsyncNever leaves flushing to the operating system
+	FsyncNever
+)
+
+// kinds of log records
+const (
+	walPush byte = 1 // an element was pushed, seq and encoded value
+	walPop  byte = 2 // the element at the head was popped, seq
+)
+
+// a record is kind, payload length and checksum, then the payload
+const walHeader = 1 + 4 + 4
+
+const walSuffix = ".wal"
+
+// walSegment is one file of the log. it holds the pushes
+// numbered first and up, and pops of any element
+type walSegment struct {
+	first uint64 // seq of the first push the segment may hold
+	path  string
+}
+
+// walRecord is a record read back from the log
+type walRecord struct {
+	kind  byte
+	seq   uint64
+	value []byte // encoded element of a push
+}
+
+// walLog is a write-ahead log split into segments named by the seq
+// of their first push. a segment is deleted once all of its pushes
+// have been popped
+type walLog struct {
+	dir      string
+	segments []walSegment // oldest first, the last one is written to
+	file     *os.File     // the open last segment
+	size     int64        // bytes in the last segment
+	limit    int64        // a new segment is started past this size
+
+	mtx      sync.Mutex  // serializes writes with the interval flush
+	policy   FsyncPolicy // when to flush
+	interval time.Duration
+	dirty    bool        // records written since the last flush
+	timer    *time.Timer // pending interval flush
+}
+
+// segmentName is the file name of the segment starting at first,
+// zero padded so the names sort in seq order
+func segmentName(first uint64) string {
+	return fmt.Sprintf("%020d%s", first, walSuffix)
+}
+
+// openWAL opens the log in dir, creating it if needed, and hands
+// every record in it to replay in order. a damaged record at the
+// end of the last segment is the write cut short by a crash and
+// is cut off
+func openWAL(dir string, limit int64, policy FsyncPolicy, interval time.Duration, replay func(walRecord) error) (*walLog, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	w := &walLog{dir: dir, limit: limit, policy: policy, interval: interval}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, walSuffix) {
+			continue
+		}
+		first, err := strconv.ParseUint(strings.TrimSuffix(name, walSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		w.segments = append(w.segments, walSegment{first, filepath.Join(dir, name)})
+	}
+	sort.Slice(w.segments, func(i, j int) bool { return w.segments[i].first < w.segments[j].first })
+
+	// read them all back
+	for i, segment := range w.segments {
+		data, err := os.ReadFile(segment.path)
+		if err != nil {
+			return nil, err
+		}
+		good, err := walScan(data, replay)
+		if err != nil {
+			return nil, err
+		}
+		if good < len(data) {
+			if i < len(w.segments)-1 {
+				return nil, fmt.Errorf("%w: %s at offset %d", ErrCorrupt, segment.path, good)
+			}
+			if err := os.Truncate(segment.path, int64(good)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if len(w.segments) == 0 {
+		if err := w.create(0); err != nil {
+			return nil, err
+		}
+		return w, nil
+	}
+
+	// carry on writing the last segment
+	last := w.segments[len(w.segments)-1]
+	w.file, err = os.OpenFile(last.path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	info, err := w.file.Stat()
+	if err != nil {
+		w.file.Close()
+		return nil, err
+	}
+	w.size = info.Size()
+
+	return w, nil
+}
+
+// walScan hands the records in data to replay and returns
+// the length of the part that holds whole, undamaged records
+func walScan(data []byte, replay func(walRecord) error) (int, error) {
+	offset := 0
+	for len(data)-offset >= walHeader {
+		kind := data[offset]
+		length := int(binary.LittleEndian.Uint32(data[offset+1:]))
+		sum := binary.LittleEndian.Uint32(data[offset+5:])
+
+		end := offset + walHeader + length
+		if length < 8 || end > len(data) {
+			break
+		}
+		payload := data[offset+walHeader : end]
+		if walChecksum(kind, payload) != sum {
+			break
+		}
+		if kind != walPush && kind != walPop {
+			break
+		}
+
+		record := walRecord{kind: kind, seq: binary.LittleEndian.Uint64(payload)}
+		if kind == walPush {
+			record.value = payload[8:]
+		}
+		if err := replay(record); err != nil {
+			return offset, err
+		}
+		offset = end
+	}
+	return offset, nil
+}
+
+// walChecksum covers the kind and the payload of a record
+func walChecksum(kind byte, payload []byte) uint32 {
+	return crc32.Update(crc32.ChecksumIEEE([]byte{kind}), crc32.IEEETable, payload)
+}
+
+// create starts a new segment for pushes from first on
+func (w *walLog) create(first uint64) error {
+	path := filepath.Join(w.dir, segmentName(first))
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+
+	// the new file has to be in the directory before we rely on it
+	if err := syncDir(w.dir); err != nil && w.policy != FsyncNever {
+		file.Close()
+		return err
+	}
+
+	if w.file != nil {
+		// what's in the old one has to be on disk before it's abandoned
+		if w.policy != FsyncNever {
+			w.file.Sync()
+		}
+		w.file.Close()
+	}
+	w.file = file
+	w.size = 0
+	w.segments = append(w.segments, walSegment{first, path})
+
+	return nil
+}
+
+// append writes one record. next is the seq of the next push,
+// where a new segment would start
+func (w *walLog) append(kind byte, seq uint64, value []byte, next uint64) error {
+	// lock the mutex
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	if w.file == nil {
+		return ErrClosed
+	}
+
+	// roll over to a new segment, but only at a push
+	// so a segment is never named after a seq it doesn't hold
+	if kind == walPush && w.size >= w.limit && w.size > 0 {
+		if err := w.create(next); err != nil {
+			return err
+		}
+	}
+
+	record := make([]byte, walHeader+8+len(value))
+	record[0] = kind
+	binary.LittleEndian.PutUint32(record[1:], uint32(8+len(value)))
+	binary.LittleEndian.PutUint64(record[walHeader:], seq)
+	copy(record[walHeader+8:], value)
+	binary.LittleEndian.PutUint32(record[5:], walChecksum(kind, record[walHeader:]))
+
+	if _, err := w.file.Write(record); err != nil {
+		// don't leave half a record for the next one to follow
+		w.file.Truncate(w.size)
+		return err
+	}
+	w.size += int64(len(record))
+
+	return w.flush()
+}
+
+// flush applies the fsync policy after a write.
+// the caller must hold the mutex
+func (w *walLog) flush() error {
+	switch w.policy {
+	case FsyncAlways:
+		return w.file.Sync()
+
+	case FsyncInterval:
+		w.dirty = true
+		if w.timer == nil {
+			w.timer = time.AfterFunc(w.interval, w.tick)
+		}
+	}
+	return nil
+}
+
+// tick is the interval flush
+func (w *walLog) tick() {
+	// lock the mutex
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	w.timer = nil
+	if w.dirty && w.file != nil {
+		w.dirty = false
+		w.file.Sync()
+	}
+}
+
+// compact deletes the segments whose pushes have all been popped.
+// acked is the seq of the element at the head
+func (w *walLog) compact(acked uint64) error {
+	// lock the mutex
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	// a segment is done when the next one starts at or before the head.
+	// the last segment is never deleted
+	n := 0
+	for n < len(w.segments)-1 && w.segments[n+1].first <= acked {
+		if err := os.Remove(w.segments[n].path); err != nil {
+			return err
+		}
+		n++
+	}
+	w.segments = w.segments[n:]
+
+	return nil
+}
+
+// first is the seq of the first push the oldest segment may hold
+func (w *walLog) first() uint64 {
+	return w.segments[0].first
+}
+
+// sync flushes the log to disk
+func (w *walLog) sync() error {
+	// lock the mutex
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	if w.file == nil {
+		return ErrClosed
+	}
+	w.dirty = false
+	return w.file.Sync()
+}
+
+// close flushes and closes the log
+func (w *walLog) close() error {
+	// lock the mutex
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	if w.file == nil {
+		return nil
+	}
+	if w.timer != nil {
+		w.timer.Stop()
+		w.timer = nil
+	}
+
+	err := w.file.Sync()
+	if cerr := w.file.Close(); err == nil {
+		err = cerr
+	}
+	w.file = nil
+
+	return err
+}
+
+// syncDir flushes a directory so the files created in it survive a crash
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	return d.Sync()
+}